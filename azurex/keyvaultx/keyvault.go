@@ -101,6 +101,19 @@ func (v KeyVault) GetCertificate(ctx context.Context, certName string, secretVer
 	return cert, key, nil
 }
 
+// GetSecretValue returns the value of the latest version of a secret in the given Azure key vault.
+// It satisfies notification.SecretResolver.
+func (v KeyVault) GetSecretValue(ctx context.Context, secretName string) (string, error) {
+	res, err := v.GetSecret(ctx, v.vaultURL, secretName, "")
+	if err != nil {
+		return "", err
+	}
+	if res.Value == nil {
+		return "", fmt.Errorf("secret '%v' has no value", secretName)
+	}
+	return *res.Value, nil
+}
+
 // UploadCertificate uploads a new certificate and key pair to the given Azure key vault
 func (v KeyVault) UploadCertificate(ctx context.Context, cert *x509.Certificate, key *rsa.PrivateKey, certName string, certPassword string) error {
 	// Encode certificate to pkcs12
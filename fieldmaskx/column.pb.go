@@ -0,0 +1,190 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.12.3
+// source: fieldmaskx/column.proto
+
+package fieldmaskx
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Column maps a message field onto a database column.
+type Column struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The column to select, e.g. "u.name"
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// The joins needed to select the column, e.g. "JOIN users u ON u.id = t.user_id"
+	Joins []string `protobuf:"bytes,2,rep,name=joins,proto3" json:"joins,omitempty"`
+}
+
+func (x *Column) Reset() {
+	*x = Column{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_fieldmaskx_column_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Column) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Column) ProtoMessage() {}
+
+func (x *Column) ProtoReflect() protoreflect.Message {
+	mi := &file_fieldmaskx_column_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Column.ProtoReflect.Descriptor instead.
+func (*Column) Descriptor() ([]byte, []int) {
+	return file_fieldmaskx_column_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Column) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Column) GetJoins() []string {
+	if x != nil {
+		return x.Joins
+	}
+	return nil
+}
+
+var file_fieldmaskx_column_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*Column)(nil),
+		Field:         50601,
+		Name:          "apitools.fieldmaskx.column",
+		Tag:           "bytes,50601,opt,name=column",
+		Filename:      "fieldmaskx/column.proto",
+	},
+}
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// Declares the database column of a field, used by ColumnMapFromMessage
+	//
+	// optional apitools.fieldmaskx.Column column = 50601;
+	E_Column = &file_fieldmaskx_column_proto_extTypes[0]
+)
+
+var File_fieldmaskx_column_proto protoreflect.FileDescriptor
+
+var file_fieldmaskx_column_proto_rawDesc = []byte{
+	0x0a, 0x17, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x6d, 0x61, 0x73, 0x6b, 0x78, 0x2f, 0x63, 0x6f, 0x6c,
+	0x75, 0x6d, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x13, 0x61, 0x70, 0x69, 0x74, 0x6f,
+	0x6f, 0x6c, 0x73, 0x2e, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x6d, 0x61, 0x73, 0x6b, 0x78, 0x1a, 0x20,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f,
+	0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x22, 0x32, 0x0a, 0x06, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x6a, 0x6f, 0x69, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x6a,
+	0x6f, 0x69, 0x6e, 0x73, 0x3a, 0x54, 0x0a, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x1d,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xa9, 0x8b,
+	0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x61, 0x70, 0x69, 0x74, 0x6f, 0x6f, 0x6c, 0x73,
+	0x2e, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x6d, 0x61, 0x73, 0x6b, 0x78, 0x2e, 0x43, 0x6f, 0x6c, 0x75,
+	0x6d, 0x6e, 0x52, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x42, 0x47, 0x5a, 0x45, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x53, 0x65, 0x63, 0x75, 0x72, 0x69, 0x74,
+	0x61, 0x73, 0x43, 0x72, 0x69, 0x6d, 0x65, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x2f, 0x61, 0x70, 0x69, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x2d, 0x67, 0x6f, 0x2f, 0x66, 0x69,
+	0x65, 0x6c, 0x64, 0x6d, 0x61, 0x73, 0x6b, 0x78, 0x3b, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x6d, 0x61,
+	0x73, 0x6b, 0x78, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_fieldmaskx_column_proto_rawDescOnce sync.Once
+	file_fieldmaskx_column_proto_rawDescData = file_fieldmaskx_column_proto_rawDesc
+)
+
+func file_fieldmaskx_column_proto_rawDescGZIP() []byte {
+	file_fieldmaskx_column_proto_rawDescOnce.Do(func() {
+		file_fieldmaskx_column_proto_rawDescData = protoimpl.X.CompressGZIP(file_fieldmaskx_column_proto_rawDescData)
+	})
+	return file_fieldmaskx_column_proto_rawDescData
+}
+
+var file_fieldmaskx_column_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_fieldmaskx_column_proto_goTypes = []interface{}{
+	(*Column)(nil),                    // 0: apitools.fieldmaskx.Column
+	(*descriptorpb.FieldOptions)(nil), // 1: google.protobuf.FieldOptions
+}
+var file_fieldmaskx_column_proto_depIdxs = []int32{
+	1, // 0: apitools.fieldmaskx.column:extendee -> google.protobuf.FieldOptions
+	0, // 1: apitools.fieldmaskx.column:type_name -> apitools.fieldmaskx.Column
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	1, // [1:2] is the sub-list for extension type_name
+	0, // [0:1] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_fieldmaskx_column_proto_init() }
+func file_fieldmaskx_column_proto_init() {
+	if File_fieldmaskx_column_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_fieldmaskx_column_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Column); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_fieldmaskx_column_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_fieldmaskx_column_proto_goTypes,
+		DependencyIndexes: file_fieldmaskx_column_proto_depIdxs,
+		MessageInfos:      file_fieldmaskx_column_proto_msgTypes,
+		ExtensionInfos:    file_fieldmaskx_column_proto_extTypes,
+	}.Build()
+	File_fieldmaskx_column_proto = out.File
+	file_fieldmaskx_column_proto_rawDesc = nil
+	file_fieldmaskx_column_proto_goTypes = nil
+	file_fieldmaskx_column_proto_depIdxs = nil
+}
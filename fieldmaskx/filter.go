@@ -0,0 +1,157 @@
+package fieldmaskx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mennanov/fmutils"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	anyFullName       protoreflect.FullName = "google.protobuf.Any"
+	structFullName    protoreflect.FullName = "google.protobuf.Struct"
+	valueFullName     protoreflect.FullName = "google.protobuf.Value"
+	listValueFullName protoreflect.FullName = "google.protobuf.ListValue"
+)
+
+// Filter keeps the msg fields that are listed in the paths and clears all the rest, in the same way as fmutils.Filter.
+// In addition the paths continue into repeated and map message fields, into messages packed in google.protobuf.Any
+// and into the keys of google.protobuf.Struct values. Any messages are resolved with protoregistry.GlobalTypes,
+// an Any with a type that can't be resolved is kept as it is. If the paths are empty all the fields are kept.
+func Filter(msg proto.Message, paths []string) error {
+	return FilterWithResolver(msg, paths, protoregistry.GlobalTypes)
+}
+
+// FilterWithResolver is like Filter but resolves the messages packed in google.protobuf.Any with the given resolver.
+func FilterWithResolver(msg proto.Message, paths []string, resolver protoregistry.MessageTypeResolver) error {
+	f := filterer{resolver: resolver}
+	return f.message(fmutils.NestedMaskFromPaths(paths), msg.ProtoReflect())
+}
+
+type filterer struct {
+	resolver protoregistry.MessageTypeResolver
+}
+
+func (f filterer) message(mask fmutils.NestedMask, m protoreflect.Message) error {
+	if len(mask) == 0 {
+		return nil
+	}
+
+	switch m.Descriptor().FullName() {
+	case anyFullName:
+		return f.any(mask, m)
+	case structFullName:
+		return f.structFields(mask, m)
+	case valueFullName:
+		return f.value(mask, m)
+	case listValueFullName:
+		return f.list(mask, m.Get(m.Descriptor().Fields().ByName("values")).List())
+	}
+
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		sub, ok := mask[string(fd.Name())]
+		if !ok {
+			m.Clear(fd)
+			return true
+		}
+
+		switch {
+		case len(sub) == 0:
+		case fd.IsList() && fd.Kind() == protoreflect.MessageKind:
+			err = f.list(sub, v.List())
+		case fd.IsMap() && fd.MapValue().Kind() == protoreflect.MessageKind:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				err = f.message(sub, mv.Message())
+				return err == nil
+			})
+		case fd.Kind() == protoreflect.MessageKind:
+			err = f.message(sub, v.Message())
+		}
+		return err == nil
+	})
+	return err
+}
+
+func (f filterer) list(mask fmutils.NestedMask, l protoreflect.List) error {
+	for i := 0; i < l.Len(); i++ {
+		if err := f.message(mask, l.Get(i).Message()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// any unpacks the message in a google.protobuf.Any, filters it and packs it again
+func (f filterer) any(mask fmutils.NestedMask, m protoreflect.Message) error {
+	fields := m.Descriptor().Fields()
+	typeURL := m.Get(fields.ByName("type_url")).String()
+	valueField := fields.ByName("value")
+	if typeURL == "" {
+		return nil
+	}
+
+	mt, err := f.resolver.FindMessageByURL(typeURL)
+	if errors.Is(err, protoregistry.NotFound) {
+		// the payload is opaque to this service, keep it like fmutils does
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not resolve %s: %w", typeURL, err)
+	}
+
+	packed := mt.New()
+	if err := proto.Unmarshal(m.Get(valueField).Bytes(), packed.Interface()); err != nil {
+		return fmt.Errorf("could not unpack %s: %w", typeURL, err)
+	}
+
+	if err := f.message(mask, packed); err != nil {
+		return err
+	}
+
+	value, err := proto.MarshalOptions{Deterministic: true}.Marshal(packed.Interface())
+	if err != nil {
+		return fmt.Errorf("could not pack %s: %w", typeURL, err)
+	}
+	m.Set(valueField, protoreflect.ValueOfBytes(value))
+
+	return nil
+}
+
+// structFields keeps the keys of a google.protobuf.Struct that are in the mask
+func (f filterer) structFields(mask fmutils.NestedMask, m protoreflect.Message) error {
+	fields := m.Get(m.Descriptor().Fields().ByName("fields")).Map()
+
+	var remove []protoreflect.MapKey
+	var err error
+	fields.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+		sub, ok := mask[k.String()]
+		if !ok {
+			remove = append(remove, k)
+			return true
+		}
+		err = f.message(sub, v.Message())
+		return err == nil
+	})
+
+	for _, k := range remove {
+		fields.Clear(k)
+	}
+	return err
+}
+
+// value applies the mask to the struct or the structs in the list of a google.protobuf.Value,
+// other kinds of values have no fields and are kept as they are
+func (f filterer) value(mask fmutils.NestedMask, m protoreflect.Message) error {
+	fields := m.Descriptor().Fields()
+	if fd := fields.ByName("struct_value"); m.Has(fd) {
+		return f.structFields(mask, m.Get(fd).Message())
+	}
+	if fd := fields.ByName("list_value"); m.Has(fd) {
+		return f.message(mask, m.Get(fd).Message())
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package fieldmaskx_test
+
+import (
+	"testing"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/fieldmaskx"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func mustAny(t *testing.T, m proto.Message) *anypb.Any {
+	a, err := anypb.New(m)
+	if err != nil {
+		t.Fatalf("could not pack: %v", err)
+	}
+	return a
+}
+
+func mustStruct(t *testing.T, v map[string]interface{}) *structpb.Struct {
+	s, err := structpb.NewStruct(v)
+	if err != nil {
+		t.Fatalf("could not build struct: %v", err)
+	}
+	return s
+}
+
+func Test_Filter(t *testing.T) {
+	event := map[string]interface{}{
+		"id":   "1",
+		"user": map[string]interface{}{"name": "a", "mail": "b"},
+		"tags": []interface{}{map[string]interface{}{"key": "k", "value": "v"}},
+	}
+
+	for _, tc := range []struct {
+		name  string
+		in    proto.Message
+		paths []string
+		want  proto.Message
+	}{
+		{
+			"repeated message fields",
+			&apipb.Api{Name: "api", Methods: []*apipb.Method{{Name: "a", RequestTypeUrl: "b"}}},
+			[]string{"methods.name"},
+			&apipb.Api{Methods: []*apipb.Method{{Name: "a"}}},
+		},
+		{
+			"message packed in an Any",
+			&typepb.Option{Name: "opt", Value: mustAny(t, &apipb.Method{Name: "a", RequestTypeUrl: "b"})},
+			[]string{"value.request_type_url"},
+			&typepb.Option{Value: mustAny(t, &apipb.Method{RequestTypeUrl: "b"})},
+		},
+		{
+			"struct keys",
+			mustStruct(t, event),
+			[]string{"id", "user.name"},
+			mustStruct(t, map[string]interface{}{"id": "1", "user": map[string]interface{}{"name": "a"}}),
+		},
+		{
+			"structs in a list value",
+			mustStruct(t, event),
+			[]string{"tags.key"},
+			mustStruct(t, map[string]interface{}{"tags": []interface{}{map[string]interface{}{"key": "k"}}}),
+		},
+		{
+			"struct packed in an Any",
+			&typepb.Option{Name: "opt", Value: mustAny(t, mustStruct(t, event))},
+			[]string{"value.user.mail"},
+			&typepb.Option{Value: mustAny(t, mustStruct(t, map[string]interface{}{"user": map[string]interface{}{"mail": "b"}}))},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := fieldmaskx.Filter(tc.in, tc.paths); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !proto.Equal(tc.in, tc.want) {
+				t.Errorf("expected: %v, got: %v", tc.want, tc.in)
+			}
+		})
+	}
+}
+
+func Test_FilterWithResolver_UnknownType(t *testing.T) {
+	value := mustAny(t, &apipb.Method{Name: "a", RequestTypeUrl: "b"})
+	msg := &typepb.Option{Name: "opt", Value: value}
+	want := proto.Clone(msg)
+	if err := fieldmaskx.FilterWithResolver(msg, []string{"value.name"}, new(protoregistry.Types)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want.(*typepb.Option).Name = ""
+	if !proto.Equal(msg, want) {
+		t.Errorf("expected the unresolvable Any to be kept, got: %v", msg)
+	}
+}
@@ -2,13 +2,15 @@ package fieldmaskx
 
 import (
 	"context"
+	"strings"
+
 	"google.golang.org/grpc/metadata"
 
 	"google.golang.org/protobuf/proto"
 
-	"github.com/mennanov/fmutils"
-
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
@@ -19,48 +21,78 @@ type FieldMaskable interface {
 
 // UnaryServerInterceptor returns a new unary server interceptor for applying request field mask on response.
 // The validity of the field mask is not checked in this method, the check need to be implemented by the user.
-func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := evaluateOptions(opts)
+
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+		if !o.shouldMask(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
 		// get the response
 		resp, err := handler(ctx, req)
 		if err != nil {
 			return resp, err
 		}
 
-		if sub, ok := req.(FieldMaskable); ok {
-			// cast to proto message if possible
-			protoResp, isProtoResponse := resp.(proto.Message)
-			if !isProtoResponse {
-				return resp, err
-			}
+		mask := o.effectiveMask(info.FullMethod, o.extractMask(ctx, req))
+		if len(mask.GetPaths()) == 0 {
+			return resp, err
+		}
 
-			// filter the response
-			fmutils.Filter(protoResp, sub.GetFieldMask().GetPaths())
+		// cast to proto message if possible
+		protoResp, isProtoResponse := resp.(proto.Message)
+		if !isProtoResponse {
+			return resp, err
+		}
 
-			// set the filtered response
-			resp = protoResp
+		// filter the response
+		if err := FilterWithResolver(protoResp, mask.GetPaths(), o.resolver); err != nil {
+			return nil, status.Errorf(codes.Internal, "could not apply field mask: %v", err)
 		}
 
-		return resp, err
+		// echo the mask, the response is still valid if the header can't be set so the error is ignored
+		if o.headerKey != "" {
+			_ = grpc.SetHeader(ctx, maskHeader(o.headerKey, mask))
+		}
+
+		return protoResp, err
 	}
 }
 
 // StreamServerInterceptor returns a new streaming server interceptor for applying request field mask on response.
 // The validity of the field mask is not checked in this method, the check need to be implemented by the user.
-func StreamServerInterceptor() grpc.StreamServerInterceptor {
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := evaluateOptions(opts)
+
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		if !o.shouldMask(info.FullMethod) {
+			return handler(srv, stream)
+		}
+
 		s := &fieldMaskStream{
 			wrappedStream: stream,
+			opts:          o,
+			fullMethod:    info.FullMethod,
+			mask:          o.effectiveMask(info.FullMethod, nil),
 		}
 
 		return handler(srv, s)
 	}
 }
 
+// maskHeader returns the header metadata used to echo the effective mask
+func maskHeader(key string, mask *fieldmaskpb.FieldMask) metadata.MD {
+	return metadata.Pairs(key, strings.Join(mask.GetPaths(), ","))
+}
+
 // Wraps a StreamServer to filter values with a requested field mask
 type fieldMaskStream struct {
 	wrappedStream grpc.ServerStream
+	opts          *options
+	fullMethod    string
 	mask          *fieldmaskpb.FieldMask
+	headerSet     bool
 }
 
 func (w *fieldMaskStream) RecvMsg(m interface{}) error {
@@ -68,8 +100,8 @@ func (w *fieldMaskStream) RecvMsg(m interface{}) error {
 		return err
 	}
 
-	if sub, ok := m.(FieldMaskable); ok {
-		w.mask = sub.GetFieldMask()
+	if requested := w.opts.extractMask(w.Context(), m); requested != nil {
+		w.mask = w.opts.effectiveMask(w.fullMethod, requested)
 	}
 
 	return nil
@@ -77,12 +109,20 @@ func (w *fieldMaskStream) RecvMsg(m interface{}) error {
 
 func (w *fieldMaskStream) SendMsg(m interface{}) error {
 	protoMsg, isProto := m.(proto.Message)
-	if !isProto {
+	if !isProto || len(w.mask.GetPaths()) == 0 {
 		return w.wrappedStream.SendMsg(m)
 	}
 
 	// filter the response
-	fmutils.Filter(protoMsg, w.mask.GetPaths())
+	if err := FilterWithResolver(protoMsg, w.mask.GetPaths(), w.opts.resolver); err != nil {
+		return status.Errorf(codes.Internal, "could not apply field mask: %v", err)
+	}
+
+	// echo the mask before the first message, the headers are sent together with it
+	if w.opts.headerKey != "" && !w.headerSet {
+		w.headerSet = true
+		_ = w.wrappedStream.SetHeader(maskHeader(w.opts.headerKey, w.mask))
+	}
 
 	// send the filtered response
 	return w.wrappedStream.SendMsg(protoMsg)
@@ -0,0 +1,91 @@
+package fieldmaskx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/fieldmaskx"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+const testMethod = "/test.Service/Get"
+
+type maskedRequest struct {
+	mask *fieldmaskpb.FieldMask
+}
+
+func (r maskedRequest) GetFieldMask() *fieldmaskpb.FieldMask {
+	return r.mask
+}
+
+func Test_UnaryServerInterceptor(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []fieldmaskx.Option
+		req  interface{}
+		want *apipb.Method
+	}{
+		{
+			"no options applies the requested mask",
+			nil,
+			maskedRequest{&fieldmaskpb.FieldMask{Paths: []string{"name"}}},
+			&apipb.Method{Name: "a"},
+		},
+		{
+			"no mask keeps the full response",
+			nil,
+			maskedRequest{},
+			&apipb.Method{Name: "a", RequestTypeUrl: "b", ResponseTypeUrl: "c"},
+		},
+		{
+			"default mask is used when no mask is requested",
+			[]fieldmaskx.Option{fieldmaskx.WithDefaultMask(testMethod, "request_type_url")},
+			maskedRequest{},
+			&apipb.Method{RequestTypeUrl: "b"},
+		},
+		{
+			"requested mask takes precedence over the default mask",
+			[]fieldmaskx.Option{fieldmaskx.WithDefaultMask(testMethod, "request_type_url")},
+			maskedRequest{&fieldmaskpb.FieldMask{Paths: []string{"name"}}},
+			&apipb.Method{Name: "a"},
+		},
+		{
+			"methods not in the allow list are not masked",
+			[]fieldmaskx.Option{fieldmaskx.WithAllowedMethods("/test.Service/Other")},
+			maskedRequest{&fieldmaskpb.FieldMask{Paths: []string{"name"}}},
+			&apipb.Method{Name: "a", RequestTypeUrl: "b", ResponseTypeUrl: "c"},
+		},
+		{
+			"denied methods are not masked",
+			[]fieldmaskx.Option{fieldmaskx.WithAllowedMethods(testMethod), fieldmaskx.WithDeniedMethods(testMethod)},
+			maskedRequest{&fieldmaskpb.FieldMask{Paths: []string{"name"}}},
+			&apipb.Method{Name: "a", RequestTypeUrl: "b", ResponseTypeUrl: "c"},
+		},
+		{
+			"custom extractor",
+			[]fieldmaskx.Option{fieldmaskx.WithMaskExtractor(func(ctx context.Context, req interface{}) *fieldmaskpb.FieldMask {
+				return &fieldmaskpb.FieldMask{Paths: []string{req.(string)}}
+			})},
+			"response_type_url",
+			&apipb.Method{ResponseTypeUrl: "c"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			interceptor := fieldmaskx.UnaryServerInterceptor(tc.opts...)
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return &apipb.Method{Name: "a", RequestTypeUrl: "b", ResponseTypeUrl: "c"}, nil
+			}
+
+			got, err := interceptor(context.Background(), tc.req, &grpc.UnaryServerInfo{FullMethod: testMethod}, handler)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !proto.Equal(got.(proto.Message), tc.want) {
+				t.Errorf("expected: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package fieldmaskx
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// MaskExtractorFunc returns the field mask that should be applied to the response of a request.
+// Returning a nil or empty mask means that the request did not ask for a mask.
+type MaskExtractorFunc func(ctx context.Context, req interface{}) *fieldmaskpb.FieldMask
+
+type Option func(*options)
+
+type options struct {
+	allowedMethods map[string]bool
+	deniedMethods  map[string]bool
+	extractMask    MaskExtractorFunc
+	defaultMasks   map[string]*fieldmaskpb.FieldMask
+	headerKey      string
+	resolver       protoregistry.MessageTypeResolver
+}
+
+// WithAllowedMethods restricts the masking to the given full method names, e.g. "/pkg.Service/Method".
+// Responses from all other methods are returned untouched.
+func WithAllowedMethods(fullMethods ...string) Option {
+	return func(o *options) {
+		for _, m := range fullMethods {
+			o.allowedMethods[m] = true
+		}
+	}
+}
+
+// WithDeniedMethods disables the masking for the given full method names, e.g. "/pkg.Service/Method".
+// A denied method is never masked, even if it is also allowed.
+func WithDeniedMethods(fullMethods ...string) Option {
+	return func(o *options) {
+		for _, m := range fullMethods {
+			o.deniedMethods[m] = true
+		}
+	}
+}
+
+// WithMaskExtractor replaces the default extractor, which reads the mask from requests implementing FieldMaskable.
+func WithMaskExtractor(f MaskExtractorFunc) Option {
+	return func(o *options) {
+		o.extractMask = f
+	}
+}
+
+// WithDefaultMask sets the mask to apply to the responses of a full method when the client does not send one.
+// This can e.g. be used to make list endpoints return summary fields only.
+func WithDefaultMask(fullMethod string, paths ...string) Option {
+	return func(o *options) {
+		o.defaultMasks[fullMethod] = &fieldmaskpb.FieldMask{Paths: paths}
+	}
+}
+
+// WithMaskHeader echoes the effective mask back to the client in the response header metadata under the given key.
+// The paths are sent as a comma separated list. Nothing is sent when no mask was applied.
+func WithMaskHeader(key string) Option {
+	return func(o *options) {
+		o.headerKey = key
+	}
+}
+
+// WithResolver sets the resolver used to find the types of messages packed in google.protobuf.Any.
+// The default is protoregistry.GlobalTypes.
+func WithResolver(r protoregistry.MessageTypeResolver) Option {
+	return func(o *options) {
+		o.resolver = r
+	}
+}
+
+func evaluateOptions(opts []Option) *options {
+	o := &options{
+		allowedMethods: map[string]bool{},
+		deniedMethods:  map[string]bool{},
+		extractMask:    extractFieldMask,
+		defaultMasks:   map[string]*fieldmaskpb.FieldMask{},
+		resolver:       protoregistry.GlobalTypes,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// extractFieldMask is the default MaskExtractorFunc
+func extractFieldMask(_ context.Context, req interface{}) *fieldmaskpb.FieldMask {
+	if sub, ok := req.(FieldMaskable); ok {
+		return sub.GetFieldMask()
+	}
+	return nil
+}
+
+// shouldMask reports whether responses from the full method should be masked
+func (o *options) shouldMask(fullMethod string) bool {
+	if o.deniedMethods[fullMethod] {
+		return false
+	}
+	if len(o.allowedMethods) > 0 {
+		return o.allowedMethods[fullMethod]
+	}
+	return true
+}
+
+// effectiveMask returns the requested mask, or the default mask of the method if none was requested
+func (o *options) effectiveMask(fullMethod string, requested *fieldmaskpb.FieldMask) *fieldmaskpb.FieldMask {
+	if len(requested.GetPaths()) > 0 {
+		return requested
+	}
+	return o.defaultMasks[fullMethod]
+}
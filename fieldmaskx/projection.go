@@ -0,0 +1,109 @@
+package fieldmaskx
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+var ErrUnmappedPath = errors.New("field mask path is not mapped to a column")
+
+// ColumnMap maps field paths of a message, e.g. "user.name", onto database columns
+type ColumnMap map[string]*Column
+
+// Projection is the database side of a field mask.
+// Columns and Fields are aligned, Fields[i] is the field path populated by Columns[i].
+type Projection struct {
+	Columns []string
+	Fields  []string
+	Joins   []string
+}
+
+// ColumnMapFromMessage builds a ColumnMap from the (apitools.fieldmaskx.column) options of the message fields.
+// Message fields without the option are walked to find mapped fields in the nested message.
+func ColumnMapFromMessage(msg proto.Message) ColumnMap {
+	m := ColumnMap{}
+	addColumns(m, msg.ProtoReflect().Descriptor(), "", map[protoreflect.FullName]bool{})
+	return m
+}
+
+func addColumns(m ColumnMap, md protoreflect.MessageDescriptor, prefix string, visiting map[protoreflect.FullName]bool) {
+	// guard against recursive messages
+	if visiting[md.FullName()] {
+		return
+	}
+	visiting[md.FullName()] = true
+	defer delete(visiting, md.FullName())
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := prefix + string(fd.Name())
+
+		if opts := fd.Options(); opts != nil && proto.HasExtension(opts, E_Column) {
+			m[path] = proto.GetExtension(opts, E_Column).(*Column)
+			continue
+		}
+
+		if fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() {
+			addColumns(m, fd.Message(), path+".", visiting)
+		}
+	}
+}
+
+// Project returns the columns and joins needed to populate the fields in the mask, so the mask can be
+// pushed down to the database. An empty mask selects all mapped columns.
+// A path selects the columns mapped on the path itself, on fields nested below it and on the message it is nested in.
+// An ErrUnmappedPath error is returned if no column is found for a path.
+func (m ColumnMap) Project(mask *fieldmaskpb.FieldMask) (Projection, error) {
+	mapped := make([]string, 0, len(m))
+	for path := range m {
+		mapped = append(mapped, path)
+	}
+	sort.Strings(mapped)
+
+	var selected []string
+	if len(mask.GetPaths()) == 0 {
+		selected = mapped
+	} else {
+		for _, path := range mask.GetPaths() {
+			found := false
+			for _, candidate := range mapped {
+				if candidate == path || strings.HasPrefix(candidate, path+".") || strings.HasPrefix(path, candidate+".") {
+					selected = append(selected, candidate)
+					found = true
+				}
+			}
+			if !found {
+				return Projection{}, fmt.Errorf("%w: %s", ErrUnmappedPath, path)
+			}
+		}
+	}
+
+	var p Projection
+	seenFields := map[string]bool{}
+	seenJoins := map[string]bool{}
+	for _, path := range selected {
+		if seenFields[path] {
+			continue
+		}
+		seenFields[path] = true
+
+		col := m[path]
+		p.Columns = append(p.Columns, col.GetName())
+		p.Fields = append(p.Fields, path)
+		for _, join := range col.GetJoins() {
+			if !seenJoins[join] {
+				seenJoins[join] = true
+				p.Joins = append(p.Joins, join)
+			}
+		}
+	}
+
+	return p, nil
+}
@@ -0,0 +1,119 @@
+package fieldmaskx_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/fieldmaskx"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+var testColumns = fieldmaskx.ColumnMap{
+	"id":         {Name: "t.id"},
+	"title":      {Name: "t.title"},
+	"owner.name": {Name: "u.name", Joins: []string{"JOIN users u ON u.id = t.owner_id"}},
+	"owner.mail": {Name: "u.mail", Joins: []string{"JOIN users u ON u.id = t.owner_id"}},
+	"details":    {Name: "t.details"},
+}
+
+func Test_ColumnMap_Project(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		paths []string
+		want  fieldmaskx.Projection
+	}{
+		{
+			"empty mask selects all columns",
+			nil,
+			fieldmaskx.Projection{
+				Columns: []string{"t.details", "t.id", "u.mail", "u.name", "t.title"},
+				Fields:  []string{"details", "id", "owner.mail", "owner.name", "title"},
+				Joins:   []string{"JOIN users u ON u.id = t.owner_id"},
+			},
+		},
+		{
+			"only the masked columns are selected",
+			[]string{"title", "id"},
+			fieldmaskx.Projection{
+				Columns: []string{"t.title", "t.id"},
+				Fields:  []string{"title", "id"},
+			},
+		},
+		{
+			"parent path selects nested columns and their joins once",
+			[]string{"owner", "owner.name"},
+			fieldmaskx.Projection{
+				Columns: []string{"u.mail", "u.name"},
+				Fields:  []string{"owner.mail", "owner.name"},
+				Joins:   []string{"JOIN users u ON u.id = t.owner_id"},
+			},
+		},
+		{
+			"nested path selects the column of the parent",
+			[]string{"details.color"},
+			fieldmaskx.Projection{
+				Columns: []string{"t.details"},
+				Fields:  []string{"details"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := testColumns.Project(&fieldmaskpb.FieldMask{Paths: tc.paths})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected: %+v, got: %+v", tc.want, got)
+			}
+		})
+	}
+
+	_, err := testColumns.Project(&fieldmaskpb.FieldMask{Paths: []string{"unknown"}})
+	if !errors.Is(err, fieldmaskx.ErrUnmappedPath) {
+		t.Errorf("expected ErrUnmappedPath, got: %v", err)
+	}
+}
+
+func Test_ColumnMapFromMessage(t *testing.T) {
+	withColumn := func(name string) *descriptorpb.FieldOptions {
+		opts := &descriptorpb.FieldOptions{}
+		proto.SetExtension(opts, fieldmaskx.E_Column, &fieldmaskx.Column{Name: name})
+		return opts
+	}
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Thing"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("id"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Options: withColumn("t.id")},
+					{Name: proto.String("owner"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".test.Owner")},
+					{Name: proto.String("note"), Number: proto.Int32(3), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				},
+			},
+			{
+				Name: proto.String("Owner"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("name"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Options: withColumn("u.name")},
+				},
+			},
+		},
+	}
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("could not build descriptor: %v", err)
+	}
+
+	got := fieldmaskx.ColumnMapFromMessage(dynamicpb.NewMessage(fd.Messages().ByName("Thing")))
+	if len(got) != 2 || got["id"].GetName() != "t.id" || got["owner.name"].GetName() != "u.name" {
+		t.Errorf("unexpected column map: %v", got)
+	}
+}
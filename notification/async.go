@@ -0,0 +1,339 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrQueueFull        = errors.New("notification queue is full")
+	ErrDispatcherClosed = errors.New("notification dispatcher is closed")
+)
+
+// DropPolicy decides what happens when a message is sent to a Dispatcher with a full queue
+type DropPolicy int
+
+const (
+	// DropNewest drops the message being sent and returns ErrQueueFull
+	DropNewest DropPolicy = iota
+	// DropOldest drops the oldest message in the queue to make room for the message being sent
+	DropOldest
+	// Block waits for room in the queue until the context of Send is done
+	Block
+)
+
+// DeliveryErrorHandler is called when a Dispatcher gives up on delivering a message
+type DeliveryErrorHandler func(msg Message, err error)
+
+// DispatcherStats are the counters of a Dispatcher
+type DispatcherStats struct {
+	Queued    uint64
+	Delivered uint64
+	Retried   uint64
+	Failed    uint64
+	Dropped   uint64
+}
+
+type DispatcherOption func(*dispatcherOptions)
+
+type dispatcherOptions struct {
+	queueSize   int
+	workers     int
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	dropPolicy  DropPolicy
+	handleErr   DeliveryErrorHandler
+	sendTimeout time.Duration
+}
+
+// WithQueueSize sets the number of messages that can wait for delivery, the default is 100.
+// Values below 1 are treated as 1.
+func WithQueueSize(n int) DispatcherOption {
+	return func(o *dispatcherOptions) {
+		o.queueSize = n
+	}
+}
+
+// WithWorkers sets the number of messages that are delivered concurrently, the default is 1.
+// Values below 1 are treated as 1.
+func WithWorkers(n int) DispatcherOption {
+	return func(o *dispatcherOptions) {
+		o.workers = n
+	}
+}
+
+// WithRetries sets how many times delivery of a message is attempted and the exponential backoff between the attempts.
+// A Retry-After given by the endpoint is used instead of the backoff, it is also capped at maxDelay.
+// The default is 5 attempts with a backoff starting at one second, capped at 30 seconds.
+func WithRetries(maxAttempts int, baseDelay, maxDelay time.Duration) DispatcherOption {
+	return func(o *dispatcherOptions) {
+		o.maxAttempts = maxAttempts
+		o.baseDelay = baseDelay
+		o.maxDelay = maxDelay
+	}
+}
+
+// WithDropPolicy sets what to do when the queue is full, the default is DropNewest
+func WithDropPolicy(p DropPolicy) DispatcherOption {
+	return func(o *dispatcherOptions) {
+		o.dropPolicy = p
+	}
+}
+
+// WithDeliveryErrorHandler sets the function that is called when a message could not be delivered.
+// The default handler logs the error with the standard logger.
+func WithDeliveryErrorHandler(f DeliveryErrorHandler) DispatcherOption {
+	return func(o *dispatcherOptions) {
+		o.handleErr = f
+	}
+}
+
+// WithSendTimeout sets the timeout of each delivery attempt, the default is DefaultHTTPTimeout
+func WithSendTimeout(d time.Duration) DispatcherOption {
+	return func(o *dispatcherOptions) {
+		o.sendTimeout = d
+	}
+}
+
+// Dispatcher is a Sender that queues messages and delivers them in the background with retries.
+// Messages still in the queue are delivered when the Dispatcher is closed.
+type Dispatcher struct {
+	next Sender
+	opts dispatcherOptions
+
+	// mu protects closed, senders are counted in sending so that the queue is only closed once they are done
+	mu      sync.RWMutex
+	closed  bool
+	closing chan struct{}
+	sending sync.WaitGroup
+	queue   chan queuedMessage
+	abort   chan struct{}
+	wg      sync.WaitGroup
+
+	queued, delivered, retried, failed, dropped uint64
+}
+
+type queuedMessage struct {
+	ctx context.Context
+	msg Message
+}
+
+// NewDispatcher starts a Dispatcher delivering messages to next
+func NewDispatcher(next Sender, opts ...DispatcherOption) *Dispatcher {
+	o := dispatcherOptions{
+		queueSize:   100,
+		workers:     1,
+		maxAttempts: 5,
+		baseDelay:   time.Second,
+		maxDelay:    30 * time.Second,
+		dropPolicy:  DropNewest,
+		handleErr: func(msg Message, err error) {
+			log.Printf("notification: could not deliver %q: %v", msg.Title, err)
+		},
+		sendTimeout: DefaultHTTPTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.queueSize < 1 {
+		o.queueSize = 1
+	}
+	if o.workers < 1 {
+		o.workers = 1
+	}
+	if o.maxAttempts < 1 {
+		o.maxAttempts = 1
+	}
+
+	d := &Dispatcher{
+		next:    next,
+		opts:    o,
+		closing: make(chan struct{}),
+		queue:   make(chan queuedMessage, o.queueSize),
+		abort:   make(chan struct{}),
+	}
+
+	d.wg.Add(o.workers)
+	for i := 0; i < o.workers; i++ {
+		go d.work()
+	}
+
+	return d
+}
+
+// Send queues the message for delivery. The context is only used to wait for room in the queue
+// with the Block policy, its values are kept for the delivery.
+func (d *Dispatcher) Send(ctx context.Context, msg Message) error {
+	d.mu.RLock()
+	if d.closed {
+		d.mu.RUnlock()
+		return ErrDispatcherClosed
+	}
+	d.sending.Add(1)
+	d.mu.RUnlock()
+	defer d.sending.Done()
+
+	item := queuedMessage{ctx: DetachContext(ctx), msg: msg}
+	switch d.opts.dropPolicy {
+	case Block:
+		select {
+		case d.queue <- item:
+		case <-ctx.Done():
+			atomic.AddUint64(&d.dropped, 1)
+			return ctx.Err()
+		case <-d.closing:
+			atomic.AddUint64(&d.dropped, 1)
+			return ErrDispatcherClosed
+		}
+	case DropOldest:
+		for {
+			select {
+			case d.queue <- item:
+				atomic.AddUint64(&d.queued, 1)
+				return nil
+			default:
+			}
+
+			select {
+			case <-d.queue:
+				atomic.AddUint64(&d.dropped, 1)
+			default:
+			}
+		}
+	default:
+		select {
+		case d.queue <- item:
+		default:
+			atomic.AddUint64(&d.dropped, 1)
+			return ErrQueueFull
+		}
+	}
+
+	atomic.AddUint64(&d.queued, 1)
+	return nil
+}
+
+// Close stops accepting messages and waits until the queued messages are delivered.
+// If ctx is done first, retries are stopped, the remaining messages are dropped and the context error is returned.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	closing := !d.closed
+	if closing {
+		d.closed = true
+		close(d.closing)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		if closing {
+			// senders blocked on a full queue return when closing is closed
+			d.sending.Wait()
+			close(d.queue)
+		}
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		d.stopRetrying()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Stats returns the current counters of the dispatcher
+func (d *Dispatcher) Stats() DispatcherStats {
+	return DispatcherStats{
+		Queued:    atomic.LoadUint64(&d.queued),
+		Delivered: atomic.LoadUint64(&d.delivered),
+		Retried:   atomic.LoadUint64(&d.retried),
+		Failed:    atomic.LoadUint64(&d.failed),
+		Dropped:   atomic.LoadUint64(&d.dropped),
+	}
+}
+
+func (d *Dispatcher) stopRetrying() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-d.abort:
+	default:
+		close(d.abort)
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for item := range d.queue {
+		d.deliver(item)
+	}
+}
+
+// deliver sends the message, retrying temporary errors with backoff
+func (d *Dispatcher) deliver(item queuedMessage) {
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-d.abort:
+			atomic.AddUint64(&d.dropped, 1)
+			return
+		default:
+		}
+
+		ctx, cancel := context.WithTimeout(item.ctx, d.opts.sendTimeout)
+		err := d.next.Send(ctx, item.msg)
+		cancel()
+
+		if err == nil {
+			atomic.AddUint64(&d.delivered, 1)
+			return
+		}
+
+		if attempt >= d.opts.maxAttempts || !IsTemporary(err) {
+			atomic.AddUint64(&d.failed, 1)
+			d.opts.handleErr(item.msg, err)
+			return
+		}
+
+		atomic.AddUint64(&d.retried, 1)
+		timer := time.NewTimer(d.backoff(attempt, err))
+		select {
+		case <-timer.C:
+		case <-d.abort:
+			timer.Stop()
+			atomic.AddUint64(&d.dropped, 1)
+			return
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt, the Retry-After of the endpoint is honoured if there is one
+func (d *Dispatcher) backoff(attempt int, err error) time.Duration {
+	return backoffDelay(attempt, d.opts.baseDelay, d.opts.maxDelay, err)
+}
+
+// backoffDelay doubles baseDelay for every attempt after the first, or uses the Retry-After of the endpoint,
+// capped at maxDelay
+func backoffDelay(attempt int, baseDelay, maxDelay time.Duration, err error) time.Duration {
+	delay := baseDelay
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		delay = statusErr.RetryAfter
+	} else {
+		for i := 1; i < attempt && delay < maxDelay; i++ {
+			delay *= 2
+		}
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
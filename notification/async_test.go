@@ -0,0 +1,158 @@
+package notification_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+func Test_Dispatcher_RetriesTemporaryErrors(t *testing.T) {
+	var calls int32
+	sender := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return &notification.StatusError{StatusCode: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+
+	d := notification.NewDispatcher(sender, notification.WithRetries(5, time.Millisecond, 10*time.Millisecond))
+	if err := d.Send(context.Background(), notification.Message{Title: "a"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error on close: %v", err)
+	}
+
+	stats := d.Stats()
+	if stats.Delivered != 1 || stats.Retried != 2 || stats.Failed != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if err := d.Send(context.Background(), notification.Message{}); !errors.Is(err, notification.ErrDispatcherClosed) {
+		t.Errorf("expected ErrDispatcherClosed, got: %v", err)
+	}
+}
+
+func Test_Dispatcher_DoesNotRetryPermanentErrors(t *testing.T) {
+	var failed []error
+	sender := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		return &notification.StatusError{StatusCode: http.StatusBadRequest}
+	})
+
+	d := notification.NewDispatcher(sender,
+		notification.WithRetries(5, time.Millisecond, time.Millisecond),
+		notification.WithDeliveryErrorHandler(func(msg notification.Message, err error) {
+			failed = append(failed, err)
+		}),
+	)
+	_ = d.Send(context.Background(), notification.Message{Title: "a"})
+	_ = d.Close(context.Background())
+
+	if stats := d.Stats(); stats.Retried != 0 || stats.Failed != 1 || len(failed) != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func Test_Dispatcher_DropsWhenQueueIsFull(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	sender := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+
+	d := notification.NewDispatcher(sender, notification.WithQueueSize(1))
+	_ = d.Send(context.Background(), notification.Message{Title: "delivering"})
+	<-started
+	_ = d.Send(context.Background(), notification.Message{Title: "queued"})
+	if err := d.Send(context.Background(), notification.Message{Title: "dropped"}); !errors.Is(err, notification.ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got: %v", err)
+	}
+
+	close(release)
+	_ = d.Close(context.Background())
+
+	if stats := d.Stats(); stats.Delivered != 2 || stats.Dropped != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func Test_Dispatcher_CloseUnblocksSenders(t *testing.T) {
+	release := make(chan struct{})
+	sender := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		<-release
+		return nil
+	})
+
+	// zero workers and queue size are raised to one instead of never delivering
+	d := notification.NewDispatcher(sender, notification.WithWorkers(0), notification.WithQueueSize(0), notification.WithDropPolicy(notification.Block))
+	_ = d.Send(context.Background(), notification.Message{Title: "delivering"})
+	_ = d.Send(context.Background(), notification.Message{Title: "queued"})
+
+	blocked := make(chan error)
+	go func() {
+		blocked <- d.Send(context.Background(), notification.Message{Title: "blocked"})
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	closed := make(chan error)
+	go func() {
+		closed <- d.Close(context.Background())
+	}()
+	select {
+	case err := <-blocked:
+		if !errors.Is(err, notification.ErrDispatcherClosed) {
+			t.Errorf("expected ErrDispatcherClosed, got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected Close to unblock the sender")
+	}
+
+	close(release)
+	if err := <-closed; err != nil {
+		t.Fatalf("unexpected error on close: %v", err)
+	}
+	if stats := d.Stats(); stats.Delivered != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func Test_Dispatcher_CapsRetryAfter(t *testing.T) {
+	var calls int32
+	sender := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return &notification.StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour}
+		}
+		return nil
+	})
+
+	d := notification.NewDispatcher(sender, notification.WithRetries(2, time.Millisecond, 10*time.Millisecond))
+	_ = d.Send(context.Background(), notification.Message{Title: "a"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := d.Close(ctx); err != nil {
+		t.Fatalf("expected the Retry-After to be capped at the max delay, got: %v", err)
+	}
+}
+
+func Test_Slack_RetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	err := notification.NewSlack(server.URL, server.URL, "test", false).Alert("a", "b")
+
+	var statusErr *notification.StatusError
+	if !errors.As(err, &statusErr) || statusErr.RetryAfter != 7*time.Second || !notification.IsTemporary(err) {
+		t.Errorf("expected a temporary StatusError with a retry after, got: %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of Slack Block Kit, content beyond them is split or truncated by Blocks
+const (
+	SlackMaxBlocks         = 50
+	SlackMaxTextLength     = 3000
+	SlackMaxHeaderLength   = 150
+	SlackMaxFieldLength    = 2000
+	SlackMaxFields         = 10
+	SlackMaxContextItems   = 10
+	SlackMaxButtons        = 25
+	SlackMaxButtonTextSize = 75
+)
+
+const codeFence = "```"
+
+// Element is an element of a context or actions block, it is a Text, an ImageAccessory or a Button
+type Element interface {
+	element()
+}
+
+func (Text) element()           {}
+func (ImageAccessory) element() {}
+func (Button) element()         {}
+
+// Button is a button element opening a URL
+type Button struct {
+	Type  string `json:"type"`
+	Text  Text   `json:"text"`
+	URL   string `json:"url,omitempty"`
+	Style string `json:"style,omitempty"`
+}
+
+// Blocks builds Slack Block Kit blocks. Content that exceeds the limits of Slack is split into several blocks
+// where it can be, e.g. long texts and many fields, and truncated otherwise, e.g. headers and button texts.
+// Use Bodies to split the blocks into messages of at most SlackMaxBlocks blocks.
+type Blocks struct {
+	blocks []Section
+}
+
+// NewBlocks returns an empty Blocks
+func NewBlocks() *Blocks {
+	return &Blocks{}
+}
+
+// Header adds a header block with plain text
+func (b *Blocks) Header(text string) *Blocks {
+	return b.add(Section{
+		Type: "header",
+		Text: &Text{Type: "plain_text", Text: truncate(text, SlackMaxHeaderLength)},
+	})
+}
+
+// Section adds a section with mrkdwn text, split into several sections if the text is too long
+func (b *Blocks) Section(text string) *Blocks {
+	return b.SectionWithImage(text, nil)
+}
+
+// SectionWithImage adds a section with mrkdwn text and an image accessory, the image is added to the first section
+// if the text is split. Code blocks in the text are closed and reopened where it is split.
+func (b *Blocks) SectionWithImage(text string, image *ImageAccessory) *Blocks {
+	limit := SlackMaxTextLength
+	if strings.Contains(text, codeFence) {
+		limit -= 2 * len(codeFence+"\n")
+	}
+
+	open := false
+	for i, part := range splitText(text, limit) {
+		if open {
+			part = codeFence + "\n" + part
+		}
+		open = strings.Count(part, codeFence)%2 == 1
+		if open {
+			part += "\n" + codeFence
+		}
+
+		section := Section{Type: "section", Text: &Text{Type: "mrkdwn", Text: part}}
+		if i == 0 {
+			section.Accessory = image
+		}
+		b.add(section)
+	}
+	return b
+}
+
+// Fields adds sections with the fields as two columns, at most SlackMaxFields fields per section
+func (b *Blocks) Fields(fields ...Field) *Blocks {
+	for len(fields) > 0 {
+		n := len(fields)
+		if n > SlackMaxFields {
+			n = SlackMaxFields
+		}
+
+		section := Section{Type: "section"}
+		for _, f := range fields[:n] {
+			section.Fields = append(section.Fields, Text{
+				Type: "mrkdwn",
+				Text: truncate(fmt.Sprintf("*%s*\n%s", f.Key, f.Value), SlackMaxFieldLength),
+			})
+		}
+		b.add(section)
+		fields = fields[n:]
+	}
+	return b
+}
+
+// Context adds context blocks with the mrkdwn texts, in small print
+func (b *Blocks) Context(texts ...string) *Blocks {
+	elements := make([]Element, 0, len(texts))
+	for _, text := range texts {
+		elements = append(elements, Text{Type: "mrkdwn", Text: truncate(text, SlackMaxTextLength)})
+	}
+	return b.elements("context", elements, SlackMaxContextItems)
+}
+
+// Code adds the text as a code block, split into several blocks if it is too long
+func (b *Blocks) Code(text string) *Blocks {
+	text = strings.Trim(text, "\n")
+	for _, part := range splitText(text, SlackMaxTextLength-2*len(codeFence+"\n")) {
+		b.add(Section{Type: "section", Text: &Text{Type: "mrkdwn", Text: codeFence + "\n" + part + "\n" + codeFence}})
+	}
+	return b
+}
+
+// Buttons adds actions blocks with a button opening each link
+func (b *Blocks) Buttons(links ...Link) *Blocks {
+	elements := make([]Element, 0, len(links))
+	for _, link := range links {
+		elements = append(elements, Button{
+			Type: "button",
+			Text: Text{Type: "plain_text", Text: truncate(link.Text, SlackMaxButtonTextSize)},
+			URL:  link.URL,
+		})
+	}
+	return b.elements("actions", elements, SlackMaxButtons)
+}
+
+// Divider adds a divider
+func (b *Blocks) Divider() *Blocks {
+	return b.add(Section{Type: "divider"})
+}
+
+// Blocks returns all the blocks that were added
+func (b *Blocks) Blocks() []Section {
+	return b.blocks
+}
+
+// Body returns the blocks as one message. If there are more than SlackMaxBlocks blocks the last
+// blocks are replaced with a context block saying how many were left out.
+func (b *Blocks) Body() Body {
+	if len(b.blocks) <= SlackMaxBlocks {
+		return Body{Blocks: b.blocks}
+	}
+
+	blocks := append([]Section(nil), b.blocks[:SlackMaxBlocks-1]...)
+	omitted := len(b.blocks) - len(blocks)
+	blocks = append(blocks, Section{
+		Type:     "context",
+		Elements: []Element{Text{Type: "mrkdwn", Text: fmt.Sprintf("_%d more blocks were left out_", omitted)}},
+	})
+	return Body{Blocks: blocks}
+}
+
+// Bodies returns the blocks split into messages of at most SlackMaxBlocks blocks
+func (b *Blocks) Bodies() []Body {
+	var bodies []Body
+	blocks := b.blocks
+	for len(blocks) > SlackMaxBlocks {
+		bodies = append(bodies, Body{Blocks: blocks[:SlackMaxBlocks]})
+		blocks = blocks[SlackMaxBlocks:]
+	}
+	return append(bodies, Body{Blocks: blocks})
+}
+
+func (b *Blocks) add(section Section) *Blocks {
+	b.blocks = append(b.blocks, section)
+	return b
+}
+
+// elements adds blocks of the type with at most max elements each
+func (b *Blocks) elements(blockType string, elements []Element, max int) *Blocks {
+	for len(elements) > 0 {
+		n := len(elements)
+		if n > max {
+			n = max
+		}
+		b.add(Section{Type: blockType, Elements: elements[:n]})
+		elements = elements[n:]
+	}
+	return b
+}
+
+// truncate shortens the text to at most max characters, ending it with an ellipsis if it was shortened
+func truncate(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:max-1]) + "…"
+}
+
+// splitText splits the text into parts of at most max characters, preferably at line breaks and otherwise at spaces
+func splitText(text string, max int) []string {
+	var parts []string
+	runes := []rune(text)
+	for len(runes) > max {
+		cut, separator := lastIndexRune(runes[:max], '\n'), true
+		if cut <= 0 {
+			cut = lastIndexRune(runes[:max], ' ')
+		}
+		if cut <= 0 {
+			cut, separator = max, false
+		}
+		parts = append(parts, string(runes[:cut]))
+		runes = runes[cut:]
+		// the line break or space the text was split at is not kept
+		if separator {
+			runes = runes[1:]
+		}
+	}
+	return append(parts, string(runes))
+}
+
+func lastIndexRune(runes []rune, r rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,182 @@
+package notification_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+func Test_Blocks_Limits(t *testing.T) {
+	line := strings.Repeat("x", 99) + "\n"
+	var fields []notification.Field
+	for i := 0; i < 12; i++ {
+		fields = append(fields, notification.Field{Key: "key", Value: "value"})
+	}
+	var links []notification.Link
+	for i := 0; i < 30; i++ {
+		links = append(links, notification.Link{Text: strings.Repeat("l", 100), URL: "https://logs.example.com"})
+	}
+
+	blocks := notification.NewBlocks().
+		Header(strings.Repeat("h", 200)).
+		Section(strings.Repeat(line, 50)).
+		Fields(fields...).
+		Code(strings.Repeat(line, 40)).
+		Context("a", "b").
+		Buttons(links...).
+		Divider().
+		Blocks()
+
+	types := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		types = append(types, b.Type)
+	}
+	want := "header,section,section,section,section,section,section,context,actions,actions,divider"
+	if strings.Join(types, ",") != want {
+		t.Fatalf("expected blocks %s, got: %s", want, strings.Join(types, ","))
+	}
+
+	if n := utf8.RuneCountInString(blocks[0].Text.Text); n != notification.SlackMaxHeaderLength || !strings.HasSuffix(blocks[0].Text.Text, "…") {
+		t.Errorf("expected the header to be truncated, got %d characters", n)
+	}
+	for _, b := range blocks[1:3] {
+		if len(b.Text.Text) > notification.SlackMaxTextLength || strings.HasPrefix(b.Text.Text, "\n") {
+			t.Errorf("expected the text to be split at a line break, got: %d characters", len(b.Text.Text))
+		}
+	}
+	if len(blocks[3].Fields) != 10 || len(blocks[4].Fields) != 2 {
+		t.Errorf("expected the fields to be split into sections of 10")
+	}
+	for _, b := range blocks[5:7] {
+		if len(b.Text.Text) > notification.SlackMaxTextLength || !strings.HasPrefix(b.Text.Text, "```\n") || !strings.HasSuffix(b.Text.Text, "\n```") {
+			t.Errorf("expected code blocks within the limit, got: %q", b.Text.Text)
+		}
+	}
+	if len(blocks[8].Elements) != 25 || len(blocks[9].Elements) != 5 {
+		t.Errorf("expected the buttons to be split into actions of 25")
+	}
+	if b := blocks[8].Elements[0].(notification.Button); utf8.RuneCountInString(b.Text.Text) != notification.SlackMaxButtonTextSize || b.URL != "https://logs.example.com" {
+		t.Errorf("unexpected button: %+v", b)
+	}
+}
+
+func Test_Blocks_CodeInSection(t *testing.T) {
+	text := "panic\n```\n" + strings.Repeat(strings.Repeat("x", 99)+"\n", 40) + "```"
+	blocks := notification.NewBlocks().Section(text).Blocks()
+	if len(blocks) != 2 {
+		t.Fatalf("expected the text to be split in two, got: %d", len(blocks))
+	}
+	for _, b := range blocks {
+		if strings.Count(b.Text.Text, "```")%2 != 0 || len(b.Text.Text) > notification.SlackMaxTextLength {
+			t.Errorf("expected the code block to be closed in every section, got: %q", b.Text.Text)
+		}
+	}
+}
+
+func Test_Blocks_Bodies(t *testing.T) {
+	b := notification.NewBlocks()
+	for i := 0; i < 120; i++ {
+		b.Divider()
+	}
+
+	bodies := b.Bodies()
+	if len(bodies) != 3 || len(bodies[0].Blocks) != 50 || len(bodies[2].Blocks) != 20 {
+		t.Errorf("expected 3 bodies of at most 50 blocks, got: %d", len(bodies))
+	}
+
+	body := b.Body()
+	if len(body.Blocks) != 50 || body.Blocks[49].Type != "context" {
+		t.Fatalf("expected 50 blocks ending with a note, got: %d", len(body.Blocks))
+	}
+	if text := body.Blocks[49].Elements[0].(notification.Text).Text; text != "_71 more blocks were left out_" {
+		t.Errorf("unexpected note: %q", text)
+	}
+}
+
+func Test_Slack_LinksAndSplitting(t *testing.T) {
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("could not decode payload: %v", err)
+		}
+		received = append(received, body)
+	}))
+	defer server.Close()
+
+	slack := notification.NewSlack(server.URL, server.URL, "prod", false)
+	err := slack.Send(context.Background(), notification.Message{
+		Severity: notification.SeverityAlert,
+		Title:    "big",
+		Body:     strings.Repeat(strings.Repeat("x", 99)+"\n", 2000),
+		Links:    []notification.Link{{Text: "Open logs", URL: "https://logs.example.com/q=1"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("expected the message to be posted in two parts, got: %d", len(received))
+	}
+	payload, _ := json.Marshal(received)
+	if !strings.Contains(string(payload), `{"text":{"text":"Open logs","type":"plain_text"},"type":"button","url":"https://logs.example.com/q=1"}`) {
+		t.Errorf("expected a button for the link, got: %s", payload)
+	}
+}
+
+func Test_Slack_SplitRetry(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		// the second part fails once
+		if len(received) == 1 && failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received = append(received, string(body))
+	}))
+	defer server.Close()
+
+	slack := notification.NewSlack(server.URL, server.URL, "prod", false)
+	msg := notification.Message{
+		Severity: notification.SeverityAlert,
+		Title:    "big",
+		Body:     strings.Repeat(strings.Repeat("x", 99)+"\n", 2000),
+	}
+	if err := slack.Send(context.Background(), msg); err == nil {
+		t.Fatalf("expected the failing part to return an error")
+	}
+	if err := slack.Send(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	posted := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		parts := received
+		received = nil
+		return parts
+	}
+	if parts := posted(); len(parts) != 2 || parts[0] == parts[1] {
+		t.Errorf("expected the retry to post only the part that failed, got %d parts", len(parts))
+	}
+
+	// the message is complete, sending it again posts all of it
+	_ = slack.Send(context.Background(), msg)
+	if parts := posted(); len(parts) != 2 {
+		t.Errorf("expected both parts of a new send, got: %d", len(parts))
+	}
+}
@@ -0,0 +1,268 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by a Breaker without a fallback while its circuit is open
+var ErrCircuitOpen = errors.New("notification circuit is open")
+
+// LabelBreaker is the name of the Breaker a degraded or recovered message is about
+const LabelBreaker = "breaker"
+
+// BreakerState is the state of the circuit of a Breaker
+type BreakerState int
+
+const (
+	// BreakerClosed sends messages to the primary sender and counts the failures
+	BreakerClosed BreakerState = iota
+	// BreakerOpen sends messages to the fallback until the cool-down has passed
+	BreakerOpen
+	// BreakerHalfOpen lets a few messages through to the primary sender to probe whether it works again
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("breakerstate(%d)", int(s))
+}
+
+type BreakerOption func(*Breaker)
+
+// WithBreakerName names the channel in the degraded and recovered messages, the default is "notification channel"
+func WithBreakerName(name string) BreakerOption {
+	return func(b *Breaker) {
+		b.name = name
+	}
+}
+
+// WithBreakerThreshold sets when the circuit opens: when at least minRequests messages were sent in the window
+// and the ratio of them that failed is at least failureRatio. The default is half of at least 10 messages.
+func WithBreakerThreshold(failureRatio float64, minRequests int) BreakerOption {
+	return func(b *Breaker) {
+		b.failureRatio = failureRatio
+		b.minRequests = minRequests
+	}
+}
+
+// WithBreakerWindow sets the period over which failures are counted while the circuit is closed, the default is one minute
+func WithBreakerWindow(d time.Duration) BreakerOption {
+	return func(b *Breaker) {
+		b.window = d
+	}
+}
+
+// WithBreakerCooldown sets how long the circuit stays open before it is probed, the default is 30 seconds
+func WithBreakerCooldown(d time.Duration) BreakerOption {
+	return func(b *Breaker) {
+		b.cooldown = d
+	}
+}
+
+// WithBreakerProbes sets how many messages are let through while half-open, the circuit closes when they all succeed.
+// The default is 1.
+func WithBreakerProbes(n int) BreakerOption {
+	return func(b *Breaker) {
+		b.probes = n
+	}
+}
+
+// WithFallback sets the sender that gets the messages while the circuit is open, e.g. a LogSender.
+// Without a fallback Send returns ErrCircuitOpen while the circuit is open.
+func WithFallback(s Sender) BreakerOption {
+	return func(b *Breaker) {
+		b.fallback = s
+	}
+}
+
+// WithBreakerNotifier sets the sender of the degraded and recovered messages, the default is the fallback
+func WithBreakerNotifier(s Sender) BreakerOption {
+	return func(b *Breaker) {
+		b.notifier = s
+	}
+}
+
+// WithBreakerClock replaces the system clock, used in tests
+func WithBreakerClock(c Clock) BreakerOption {
+	return func(b *Breaker) {
+		b.clock = c
+	}
+}
+
+// Breaker is a Sender with a circuit breaker around another sender, so that an endpoint that is down doesn't
+// make every message wait for a timeout. When too many messages fail the circuit opens and the messages go to the
+// fallback sender. After the cool-down a few messages probe the primary sender, the circuit closes if they succeed
+// and opens again if they don't. When the circuit opens a "degraded" alert is sent once, and a Resolved message
+// when it closes again. Failed messages are not sent to the fallback while the circuit is closed, wrap the Breaker
+// in a Dispatcher to retry them.
+type Breaker struct {
+	next         Sender
+	fallback     Sender
+	notifier     Sender
+	name         string
+	failureRatio float64
+	minRequests  int
+	window       time.Duration
+	cooldown     time.Duration
+	probes       int
+	clock        Clock
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probing     int
+	succeeded   int
+}
+
+// NewBreaker returns a Breaker around next
+func NewBreaker(next Sender, opts ...BreakerOption) *Breaker {
+	b := &Breaker{
+		next:         next,
+		name:         "notification channel",
+		failureRatio: 0.5,
+		minRequests:  10,
+		window:       time.Minute,
+		cooldown:     30 * time.Second,
+		probes:       1,
+		clock:        SystemClock(),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.notifier == nil {
+		b.notifier = b.fallback
+	}
+	if b.probes < 1 {
+		b.probes = 1
+	}
+	if b.minRequests < 1 {
+		b.minRequests = 1
+	}
+	b.windowStart = b.clock.Now()
+	return b
+}
+
+// State returns the current state of the circuit
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.clock.Now().Sub(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Send sends the message to the primary sender, or to the fallback while the circuit is open
+func (b *Breaker) Send(ctx context.Context, msg Message) error {
+	allowed, probe := b.allow()
+	if !allowed {
+		if b.fallback == nil {
+			return ErrCircuitOpen
+		}
+		return b.fallback.Send(ctx, msg)
+	}
+
+	err := b.next.Send(ctx, msg)
+	b.record(ctx, err, probe)
+	return err
+}
+
+// allow reports whether a message can go to the primary sender and whether it is a probe of a half-open circuit
+func (b *Breaker) allow() (allowed, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false, false
+		}
+		b.state = BreakerHalfOpen
+		b.probing, b.succeeded = 0, 0
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probing >= b.probes {
+			return false, false
+		}
+		b.probing++
+		return true, true
+	}
+
+	if now.Sub(b.windowStart) >= b.window {
+		b.windowStart, b.requests, b.failures = now, 0, 0
+	}
+	return true, false
+}
+
+func (b *Breaker) record(ctx context.Context, err error, probe bool) {
+	b.mu.Lock()
+	var notify *Message
+	switch {
+	case probe && b.state == BreakerHalfOpen && err != nil:
+		// still down, wait for another cool-down
+		b.state, b.openedAt = BreakerOpen, b.clock.Now()
+	case probe && b.state == BreakerHalfOpen:
+		b.succeeded++
+		if b.succeeded >= b.probes {
+			b.state = BreakerClosed
+			b.windowStart, b.requests, b.failures = b.clock.Now(), 0, 0
+			notify = b.recovered()
+		}
+	case !probe && b.state == BreakerClosed:
+		b.requests++
+		if err != nil {
+			b.failures++
+		}
+		if b.requests >= b.minRequests && float64(b.failures)/float64(b.requests) >= b.failureRatio {
+			b.state, b.openedAt = BreakerOpen, b.clock.Now()
+			notify = b.degraded(err)
+		}
+	}
+	b.mu.Unlock()
+
+	if notify != nil && b.notifier != nil {
+		if err := b.notifier.Send(DetachContext(ctx), *notify); err != nil {
+			log.Printf("notification: could not send %q: %v", notify.Title, err)
+		}
+	}
+}
+
+func (b *Breaker) degraded(err error) *Message {
+	return &Message{
+		Severity:    SeverityAlert,
+		Title:       "Notification channel degraded: " + b.name,
+		Body:        fmt.Sprintf("%d of the last %d messages failed, messages are sent to the fallback and the channel is retried in %s", b.failures, b.requests, formatDuration(b.cooldown)),
+		Err:         err,
+		Labels:      map[string]string{LabelBreaker: b.name},
+		Fingerprint: "breaker/" + b.name,
+		Timestamp:   b.clock.Now(),
+	}
+}
+
+func (b *Breaker) recovered() *Message {
+	return &Message{
+		Severity:    SeverityInfo,
+		Title:       "Notification channel recovered: " + b.name,
+		Body:        fmt.Sprintf("Down for %s", formatDuration(b.clock.Now().Sub(b.openedAt).Round(time.Second))),
+		Labels:      map[string]string{LabelBreaker: b.name},
+		Fingerprint: "breaker/" + b.name,
+		Resolved:    true,
+		Timestamp:   b.clock.Now(),
+	}
+}
@@ -0,0 +1,106 @@
+package notification_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+	"github.com/SecuritasCrimePrediction/apitools-go/notification/notificationtest"
+)
+
+func Test_Breaker(t *testing.T) {
+	var down int32 = 1
+	var calls int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer primary.Close()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	notifier := notificationtest.NewRecorder()
+	clock := newFakeClock()
+	breaker := notification.NewBreaker(notification.NewSlack(primary.URL, primary.URL, "prod", false),
+		notification.WithBreakerName("slack"),
+		notification.WithBreakerThreshold(0.5, 4),
+		notification.WithBreakerCooldown(time.Minute),
+		notification.WithBreakerClock(clock),
+		notification.WithFallback(notification.NewLogSender(zap.New(core))),
+		notification.WithBreakerNotifier(notifier))
+
+	ctx := context.Background()
+	msg := notification.Message{Severity: notification.SeverityAlert, Title: "db down"}
+
+	_ = breaker.Send(ctx, notification.Message{Title: "ok"})
+	for i := 0; i < 3; i++ {
+		var statusErr *notification.StatusError
+		if err := breaker.Send(ctx, msg); !errors.As(err, &statusErr) {
+			t.Fatalf("expected the error of the primary sender while closed, got: %v", err)
+		}
+	}
+	if breaker.State() != notification.BreakerOpen {
+		t.Fatalf("expected the circuit to open, got: %v", breaker.State())
+	}
+	degraded := notifier.Messages()
+	if len(degraded) != 1 || degraded[0].Title != "Notification channel degraded: slack" || degraded[0].Severity != notification.SeverityAlert {
+		t.Fatalf("expected one degraded message, got: %+v", degraded)
+	}
+
+	// while open the primary isn't called and the messages are logged
+	for i := 0; i < 5; i++ {
+		if err := breaker.Send(ctx, msg); err != nil {
+			t.Fatalf("unexpected error from the fallback: %v", err)
+		}
+	}
+	if atomic.LoadInt32(&calls) != 4 || logs.FilterMessage("db down").Len() != 5 {
+		t.Errorf("expected the messages to go to the fallback, got %d calls and %d logs", calls, logs.Len())
+	}
+	if entry := logs.All()[0]; entry.Level != zapcore.ErrorLevel {
+		t.Errorf("expected an alert to be logged as an error, got: %v", entry.Level)
+	}
+
+	// a failed probe opens the circuit for another cool-down, without another degraded message
+	clock.Advance(time.Minute)
+	if breaker.State() != notification.BreakerHalfOpen {
+		t.Fatalf("expected the circuit to be half-open, got: %v", breaker.State())
+	}
+	_ = breaker.Send(ctx, msg)
+	if breaker.State() != notification.BreakerOpen || notifier.Len() != 1 {
+		t.Errorf("expected the circuit to open again, got: %v", breaker.State())
+	}
+
+	atomic.StoreInt32(&down, 0)
+	clock.Advance(time.Minute)
+	if err := breaker.Send(ctx, msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if breaker.State() != notification.BreakerClosed {
+		t.Fatalf("expected the circuit to close, got: %v", breaker.State())
+	}
+	if recovered := notifier.Messages(); len(recovered) != 2 || !recovered[1].Resolved || recovered[1].Fingerprint != degraded[0].Fingerprint {
+		t.Errorf("expected a resolved message for the degraded one, got: %+v", recovered)
+	}
+}
+
+func Test_Breaker_WithoutFallback(t *testing.T) {
+	failing := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		return errors.New("dial tcp: i/o timeout")
+	})
+	breaker := notification.NewBreaker(failing, notification.WithBreakerThreshold(1, 1))
+
+	_ = breaker.Send(context.Background(), notification.Message{})
+	if err := breaker.Send(context.Background(), notification.Message{}); err != notification.ErrCircuitOpen {
+		t.Errorf("expected ErrCircuitOpen, got: %v", err)
+	}
+}
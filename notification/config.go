@@ -0,0 +1,900 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// Config describes the sender graph built by FromConfig. Messages go through the enrichment, the async queue, the
+// silences, the deduplicator and the routes to the senders, the stages that aren't configured are left out.
+type Config struct {
+	// Environment is shown in the messages and matched by the environments of routes and silences
+	Environment string `json:"environment,omitempty"`
+	// Source is the Source of the messages the graph sends itself, e.g. the expiry reports of silences
+	Source   string `json:"source,omitempty"`
+	TestMode bool   `json:"testMode,omitempty"`
+	// Senders are the backends by name, the names are used by the routes
+	Senders map[string]SenderSettings `json:"senders,omitempty" validate:"required,min=1,dive"`
+	Routes  []RouteSettings           `json:"routes,omitempty" validate:"dive"`
+	// Default are the senders of the messages that match no route, all the senders if it is empty
+	Default  []string          `json:"default,omitempty"`
+	Dedup    *DedupSettings    `json:"dedup,omitempty"`
+	Async    *AsyncSettings    `json:"async,omitempty"`
+	Silences []SilenceSettings `json:"silences,omitempty" validate:"dive"`
+	Enrich   *EnrichSettings   `json:"enrich,omitempty"`
+
+	// raw is the config before interpolation and vaultPaths are the paths of the values with vault secrets,
+	// they are set by ParseConfig
+	raw        *Config
+	vaultPaths map[string]bool
+}
+
+// SenderSettings configures one backend, exactly one of the backends must be set.
+// MinSeverity is the name of the lowest severity the backend is sent, see SeverityFilter.
+type SenderSettings struct {
+	MinSeverity string             `json:"minSeverity,omitempty"`
+	Slack       *SlackSettings     `json:"slack,omitempty"`
+	SlackAPI    *SlackAPISettings  `json:"slackAPI,omitempty"`
+	Teams       *TeamsSettings     `json:"teams,omitempty"`
+	Webhook     *WebhookSettings   `json:"webhook,omitempty"`
+	PagerDuty   *PagerDutySettings `json:"pagerDuty,omitempty"`
+	Email       *EmailSettings     `json:"email,omitempty"`
+}
+
+// SlackSettings configures a Slack sender, see NewSlack. The keys of SeverityHooks are severity names.
+type SlackSettings struct {
+	InfoHook      string            `json:"infohook,omitempty" validate:"required,url"`
+	AlertHook     string            `json:"alerthook,omitempty" validate:"required,url"`
+	SeverityHooks map[string]string `json:"severityHooks,omitempty" validate:"dive,url"`
+}
+
+// SlackAPISettings configures a SlackAPI sender, see NewSlackAPI. The keys of SeverityChannels are severity names.
+type SlackAPISettings struct {
+	Token            string            `json:"token,omitempty" validate:"required"`
+	InfoChannel      string            `json:"infoChannel,omitempty" validate:"required"`
+	AlertChannel     string            `json:"alertChannel,omitempty" validate:"required"`
+	SeverityChannels map[string]string `json:"severityChannels,omitempty"`
+	URL              string            `json:"url,omitempty" validate:"omitempty,url"`
+}
+
+// TeamsSettings configures a Teams sender, see NewTeams. The keys of SeverityHooks are severity names.
+type TeamsSettings struct {
+	InfoHook      string            `json:"infohook,omitempty" validate:"required,url"`
+	AlertHook     string            `json:"alerthook,omitempty" validate:"required,url"`
+	SeverityHooks map[string]string `json:"severityHooks,omitempty" validate:"dive,url"`
+}
+
+// WebhookSettings configures a Webhook sender, see WebhookConfig. The keys of SeverityURLs are severity names.
+type WebhookSettings struct {
+	URL          string            `json:"url,omitempty" validate:"omitempty,url"`
+	SeverityURLs map[string]string `json:"severityURLs,omitempty" validate:"dive,url"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Template     string            `json:"template,omitempty"`
+	ContentType  string            `json:"contentType,omitempty"`
+	Secret       string            `json:"secret,omitempty"`
+}
+
+// PagerDutySettings configures a PagerDuty sender, see NewPagerDuty
+type PagerDutySettings struct {
+	RoutingKey string `json:"routingKey,omitempty" validate:"required"`
+	EventsURL  string `json:"eventsURL,omitempty" validate:"omitempty,url"`
+}
+
+// EmailSettings configures an Email sender, see EmailConfig. The keys of SeverityTo are severity names
+// and the durations use the syntax of time.ParseDuration.
+type EmailSettings struct {
+	Addr          string              `json:"addr,omitempty" validate:"required"`
+	From          string              `json:"from,omitempty" validate:"required,email"`
+	To            []string            `json:"to,omitempty" validate:"dive,email"`
+	SeverityTo    map[string][]string `json:"severityTo,omitempty" validate:"dive,dive,email"`
+	Username      string              `json:"username,omitempty"`
+	Password      string              `json:"password,omitempty"`
+	RequireTLS    bool                `json:"requireTLS,omitempty"`
+	BatchInterval string              `json:"batchInterval,omitempty"`
+	BatchSize     int                 `json:"batchSize,omitempty" validate:"min=0"`
+	Timeout       string              `json:"timeout,omitempty"`
+}
+
+// MatchSettings configures a Match, the severities are severity names
+type MatchSettings struct {
+	MinSeverity  string            `json:"minSeverity,omitempty"`
+	Severities   []string          `json:"severities,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Sources      []string          `json:"sources,omitempty"`
+	Methods      []string          `json:"methods,omitempty"`
+	Environments []string          `json:"environments,omitempty"`
+}
+
+// RouteSettings configures a Route, Senders are names of senders
+type RouteSettings struct {
+	Match   MatchSettings `json:"match,omitempty"`
+	Senders []string      `json:"senders,omitempty" validate:"required,min=1"`
+	Stop    bool          `json:"stop,omitempty"`
+}
+
+// DedupSettings configures a Deduplicator, the window uses the syntax of time.ParseDuration
+type DedupSettings struct {
+	Window string `json:"window,omitempty"`
+}
+
+// AsyncSettings configures a Dispatcher, the durations use the syntax of time.ParseDuration.
+// The retries that are not set keep the defaults of WithRetries.
+type AsyncSettings struct {
+	QueueSize   int    `json:"queueSize,omitempty" validate:"min=0"`
+	Workers     int    `json:"workers,omitempty" validate:"min=0"`
+	MaxAttempts int    `json:"maxAttempts,omitempty" validate:"min=0"`
+	BaseDelay   string `json:"baseDelay,omitempty"`
+	MaxDelay    string `json:"maxDelay,omitempty"`
+	DropPolicy  string `json:"dropPolicy,omitempty" validate:"omitempty,oneof=dropNewest dropOldest block"`
+	SendTimeout string `json:"sendTimeout,omitempty"`
+}
+
+// SilenceSettings configures a Silence, the times use RFC 3339. Silences that have ended are ignored.
+type SilenceSettings struct {
+	Match     MatchSettings `json:"match,omitempty"`
+	StartsAt  string        `json:"startsAt,omitempty"`
+	EndsAt    string        `json:"endsAt,omitempty" validate:"required"`
+	CreatedBy string        `json:"createdBy,omitempty" validate:"required"`
+	Comment   string        `json:"comment,omitempty"`
+}
+
+// EnrichSettings configures an Enricher, see EnricherConfig
+type EnrichSettings struct {
+	Service    string         `json:"service,omitempty"`
+	Version    string         `json:"version,omitempty"`
+	Commit     string         `json:"commit,omitempty"`
+	Hostname   string         `json:"hostname,omitempty"`
+	InstanceID string         `json:"instanceID,omitempty"`
+	Links      []LinkSettings `json:"links,omitempty" validate:"dive"`
+}
+
+// LinkSettings is a LinkTemplate, the URL is a text/template
+type LinkSettings struct {
+	Text string `json:"text,omitempty" validate:"required"`
+	URL  string `json:"url,omitempty" validate:"required"`
+}
+
+// ConfigError is an error in a config, Path points at the offending value, e.g. "senders[ops].slack.infohook"
+type ConfigError struct {
+	Path string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("notification config: %v", e.Err)
+	}
+	return fmt.Sprintf("notification config: %s: %v", e.Path, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+type ConfigOption func(*configOptions)
+
+type configOptions struct {
+	lookupEnv func(string) (string, bool)
+	secrets   *Secrets
+	clock     Clock
+	handleErr DeliveryErrorHandler
+}
+
+// WithConfigLookupEnv replaces os.LookupEnv for the interpolation of variables
+func WithConfigLookupEnv(f func(string) (string, bool)) ConfigOption {
+	return func(o *configOptions) {
+		o.lookupEnv = f
+	}
+}
+
+// WithConfigSecrets resolves the ${vault:NAME} references with secrets. The senders with references are built again
+// when a refresh of the secrets changes one of their values. The secrets are not closed with the Notifier.
+func WithConfigSecrets(secrets *Secrets) ConfigOption {
+	return func(o *configOptions) {
+		o.secrets = secrets
+	}
+}
+
+// WithConfigClock replaces the system clock of the deduplicator and the silences, used in tests
+func WithConfigClock(c Clock) ConfigOption {
+	return func(o *configOptions) {
+		o.clock = c
+	}
+}
+
+// WithConfigErrorHandler sets the DeliveryErrorHandler of the stages that send in the background
+func WithConfigErrorHandler(f DeliveryErrorHandler) ConfigOption {
+	return func(o *configOptions) {
+		o.handleErr = f
+	}
+}
+
+// Notifier is the sender graph built by FromConfig
+type Notifier struct {
+	sender   Sender
+	senders  map[string]Sender
+	silencer *Silencer
+	closers  []func(context.Context) error
+}
+
+// Send sends the message through the graph
+func (n *Notifier) Send(ctx context.Context, msg Message) error {
+	return n.sender.Send(ctx, msg)
+}
+
+// Sender returns the backend with the name, e.g. to send to it directly
+func (n *Notifier) Sender(name string) (Sender, bool) {
+	s, ok := n.senders[name]
+	return s, ok
+}
+
+// Silencer returns the silencer of the graph, e.g. to manage it with silenceadmin
+func (n *Notifier) Silencer() *Silencer {
+	return n.silencer
+}
+
+// Close closes the stages from the async queue to the backends, so that the queued messages are delivered.
+// The first error is returned.
+func (n *Notifier) Close(ctx context.Context) error {
+	var first error
+	for i := len(n.closers) - 1; i >= 0; i-- {
+		if err := n.closers[i](ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// FromConfig parses a YAML or JSON config and builds its sender graph, see ParseConfig and Build
+func FromConfig(data []byte, opts ...ConfigOption) (*Notifier, error) {
+	cfg, err := ParseConfig(data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return Build(cfg, opts...)
+}
+
+// ParseConfig parses and validates a YAML or JSON config. In string values ${NAME} is replaced by the environment
+// variable NAME, which must be set, ${NAME:-default} uses default when NAME is unset or empty and $$ is a literal $.
+// ${vault:NAME} is replaced by the secret NAME, see WithConfigSecrets. The errors are ConfigErrors.
+func ParseConfig(data []byte, opts ...ConfigOption) (*Config, error) {
+	o := newConfigOptions(opts)
+
+	js, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.DisallowUnknownFields()
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, &ConfigError{Path: typeErr.Field, Err: fmt.Errorf("cannot be a %s", typeErr.Value)}
+		}
+		return nil, &ConfigError{Err: errors.New(strings.TrimPrefix(err.Error(), "json: "))}
+	}
+	// the same document decodes again, the copy is interpolated again when a secret changes
+	raw := &Config{}
+	_ = json.Unmarshal(js, raw)
+
+	e := o.expander()
+	if err := walkStrings(reflect.ValueOf(&cfg).Elem(), "", e.expand); err != nil {
+		return nil, err
+	}
+	if err := validateStruct(&cfg, ""); err != nil {
+		return nil, err
+	}
+	cfg.raw, cfg.vaultPaths = raw, e.vaultPaths
+	return &cfg, nil
+}
+
+// Render returns the config as YAML, the values with vault secrets are replaced by Redacted
+func (c *Config) Render() ([]byte, error) {
+	js, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var redacted Config
+	if err := json.Unmarshal(js, &redacted); err != nil {
+		return nil, err
+	}
+	_ = walkStrings(reflect.ValueOf(&redacted).Elem(), "", func(path, s string) (string, error) {
+		if c.vaultPaths[path] {
+			return Redacted, nil
+		}
+		return s, nil
+	})
+
+	if js, err = json.Marshal(&redacted); err != nil {
+		return nil, err
+	}
+	return yaml.JSONToYAML(js)
+}
+
+// Build builds the sender graph of a parsed config
+func Build(cfg *Config, opts ...ConfigOption) (*Notifier, error) {
+	b := configBuilder{cfg: cfg, opts: newConfigOptions(opts), n: &Notifier{senders: map[string]Sender{}}}
+	if err := b.build(); err != nil {
+		// the stages that were started are stopped
+		_ = b.n.Close(context.Background())
+		return nil, err
+	}
+	return b.n, nil
+}
+
+func newConfigOptions(opts []ConfigOption) configOptions {
+	o := configOptions{lookupEnv: os.LookupEnv, clock: SystemClock()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+var varPattern = regexp.MustCompile(`\$\$|\$\{vault:([0-9A-Za-z-]+)\}|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// expander replaces the variables and secrets in the values of a config
+type expander struct {
+	lookupEnv func(string) (string, bool)
+	// secret is nil if there are no secrets
+	secret     func(name string) (string, error)
+	vaultPaths map[string]bool
+}
+
+func (o configOptions) expander() *expander {
+	e := &expander{lookupEnv: o.lookupEnv, vaultPaths: map[string]bool{}}
+	if o.secrets != nil {
+		e.secret = func(name string) (string, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), DefaultHTTPTimeout)
+			defer cancel()
+			return o.secrets.Get(ctx, name)
+		}
+	}
+	return e
+}
+
+func (e *expander) expand(path, s string) (string, error) {
+	var err error
+	s = varPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref == "$$" {
+			return "$"
+		}
+		m := varPattern.FindStringSubmatch(ref)
+
+		if name := m[1]; name != "" {
+			e.vaultPaths[path] = true
+			if e.secret == nil {
+				if err == nil {
+					err = &ConfigError{Path: path, Err: fmt.Errorf("vault secret %s needs WithConfigSecrets", name)}
+				}
+				return ""
+			}
+			value, secretErr := e.secret(name)
+			if secretErr != nil && err == nil {
+				err = &ConfigError{Path: path, Err: secretErr}
+			}
+			return value
+		}
+
+		value, ok := e.lookupEnv(m[2])
+		if m[3] != "" {
+			if value == "" {
+				return m[3][2:]
+			}
+			return value
+		}
+		if !ok && err == nil {
+			err = &ConfigError{Path: path, Err: fmt.Errorf("environment variable %s is not set", m[2])}
+		}
+		return value
+	})
+	return s, err
+}
+
+// walkStrings replaces every string in v by the result of f, path is the config path of v
+func walkStrings(v reflect.Value, path string, f func(path, s string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return walkStrings(v.Elem(), path, f)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if err := walkStrings(v.Field(i), joinPath(path, jsonName(field)), f); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkStrings(v.Index(i), fmt.Sprintf("%s[%d]", path, i), f); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			// map values aren't addressable, they are replaced in a copy
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(key))
+			if err := walkStrings(value, fmt.Sprintf("%s[%s]", path, key), f); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, value)
+		}
+	case reflect.String:
+		s, err := f(path, v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	}
+	return nil
+}
+
+// validateStruct validates a config or a part of it, prefix is the config path of s
+func validateStruct(s interface{}, prefix string) error {
+	v := validator.New()
+	v.RegisterTagNameFunc(jsonName)
+	err := v.Struct(s)
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+	fe := fieldErrs[0]
+	// the namespace starts with the name of the type of s
+	path := fe.Namespace()
+	if i := strings.Index(path, "."); i >= 0 {
+		path = joinPath(prefix, path[i+1:])
+	}
+
+	var msg string
+	switch fe.Tag() {
+	case "required":
+		msg = "is required"
+	case "url":
+		msg = "must be a URL"
+	case "email":
+		msg = "must be an email address"
+	case "min":
+		msg = "must be at least " + fe.Param()
+	case "oneof":
+		msg = "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	default:
+		msg = fmt.Sprintf("fails the %s check", fe.Tag())
+	}
+	return &ConfigError{Path: path, Err: errors.New(msg)}
+}
+
+type configBuilder struct {
+	cfg  *Config
+	opts configOptions
+	n    *Notifier
+}
+
+func (b *configBuilder) build() error {
+	cfg := b.cfg
+
+	names := make([]string, 0, len(cfg.Senders))
+	for name := range cfg.Senders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := fmt.Sprintf("senders[%s]", name)
+		sender, err := b.sender(path, name)
+		if err != nil {
+			return err
+		}
+		if c, ok := sender.(interface{ Close(context.Context) error }); ok {
+			b.n.closers = append(b.n.closers, c.Close)
+		}
+		if min := cfg.Senders[name].MinSeverity; min != "" {
+			severity, err := ParseSeverity(min)
+			if err != nil {
+				return &ConfigError{Path: path + ".minSeverity", Err: err}
+			}
+			sender = NewSeverityFilter(sender, severity)
+		}
+		b.n.senders[name] = sender
+	}
+
+	routes := make([]Route, 0, len(cfg.Routes))
+	for i, rs := range cfg.Routes {
+		path := fmt.Sprintf("routes[%d]", i)
+		match, err := parseMatch(path+".match", rs.Match)
+		if err != nil {
+			return err
+		}
+		senders, err := b.lookup(path+".senders", rs.Senders)
+		if err != nil {
+			return err
+		}
+		routes = append(routes, Route{Match: match, Senders: senders, Stop: rs.Stop})
+	}
+	defaults, err := b.lookup("default", cfg.Default)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Default) == 0 {
+		for _, name := range names {
+			defaults = append(defaults, b.n.senders[name])
+		}
+	}
+	var sender Sender = NewRouter(routes, WithDefaultSenders(defaults...), WithRouterEnvironment(cfg.Environment))
+
+	if cfg.Dedup != nil {
+		dedupOpts := []DedupOption{WithDedupClock(b.opts.clock)}
+		if d, err := parseDuration("dedup.window", cfg.Dedup.Window); err != nil {
+			return err
+		} else if d > 0 {
+			dedupOpts = append(dedupOpts, WithDedupWindow(d))
+		}
+		if b.opts.handleErr != nil {
+			dedupOpts = append(dedupOpts, WithSummaryErrorHandler(b.opts.handleErr))
+		}
+		dedup := NewDeduplicator(sender, dedupOpts...)
+		b.n.closers = append(b.n.closers, dedup.Close)
+		sender = dedup
+	}
+
+	silencerOpts := []SilencerOption{
+		WithSilencerEnvironment(cfg.Environment),
+		WithSilencerSource(cfg.Source),
+		WithSilencerClock(b.opts.clock),
+	}
+	if b.opts.handleErr != nil {
+		silencerOpts = append(silencerOpts, WithSilencerErrorHandler(b.opts.handleErr))
+	}
+	silencer := NewSilencer(sender, silencerOpts...)
+	b.n.closers = append(b.n.closers, silencer.Close)
+	b.n.silencer = silencer
+	sender = silencer
+	for i, ss := range cfg.Silences {
+		if err := b.silence(fmt.Sprintf("silences[%d]", i), ss); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Async != nil {
+		dispatcherOpts, err := b.dispatcherOptions("async", cfg.Async)
+		if err != nil {
+			return err
+		}
+		dispatcher := NewDispatcher(sender, dispatcherOpts...)
+		b.n.closers = append(b.n.closers, dispatcher.Close)
+		sender = dispatcher
+	}
+
+	// the enricher comes first, it reads the request and trace IDs from the context of Send
+	if es := cfg.Enrich; es != nil {
+		enricherCfg := EnricherConfig{
+			Service:     es.Service,
+			Version:     es.Version,
+			Commit:      es.Commit,
+			Hostname:    es.Hostname,
+			InstanceID:  es.InstanceID,
+			Environment: cfg.Environment,
+		}
+		for i, l := range es.Links {
+			link := LinkTemplate{Text: l.Text, URL: l.URL}
+			if _, err := parseLinkTemplate(link); err != nil {
+				return &ConfigError{Path: fmt.Sprintf("enrich.links[%d].url", i), Err: err}
+			}
+			enricherCfg.Links = append(enricherCfg.Links, link)
+		}
+		enricher, err := NewEnricher(sender, enricherCfg)
+		if err != nil {
+			return &ConfigError{Path: "enrich", Err: err}
+		}
+		sender = enricher
+	}
+
+	b.n.sender = sender
+	return nil
+}
+
+// sender builds the backend with the name. A backend with vault secrets is a SecretSender, which builds it again
+// from the config before interpolation when one of the secrets changes.
+func (b *configBuilder) sender(path, name string) (Sender, error) {
+	usesVault := false
+	for p := range b.cfg.vaultPaths {
+		if strings.HasPrefix(p, path+".") {
+			usesVault = true
+		}
+	}
+	if !usesVault || b.cfg.raw == nil || b.opts.secrets == nil {
+		return b.backend(path, b.cfg.Senders[name])
+	}
+
+	return NewSecretSender(context.Background(), b.opts.secrets, func(secret func(string) string) (Sender, error) {
+		js, err := json.Marshal(b.cfg.raw.Senders[name])
+		if err != nil {
+			return nil, err
+		}
+		var settings SenderSettings
+		if err := json.Unmarshal(js, &settings); err != nil {
+			return nil, err
+		}
+
+		e := &expander{
+			lookupEnv:  b.opts.lookupEnv,
+			secret:     func(name string) (string, error) { return secret(name), nil },
+			vaultPaths: map[string]bool{},
+		}
+		if err := walkStrings(reflect.ValueOf(&settings).Elem(), path, e.expand); err != nil {
+			return nil, err
+		}
+		if err := validateStruct(&settings, path); err != nil {
+			return nil, err
+		}
+		return b.backend(path, settings)
+	})
+}
+
+func (b *configBuilder) backend(path string, s SenderSettings) (Sender, error) {
+	set := 0
+	for _, isSet := range []bool{s.Slack != nil, s.SlackAPI != nil, s.Teams != nil, s.Webhook != nil, s.PagerDuty != nil, s.Email != nil} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, &ConfigError{Path: path, Err: errors.New("must set exactly one of slack, slackAPI, teams, webhook, pagerDuty or email")}
+	}
+
+	environment, testMode := b.cfg.Environment, b.cfg.TestMode
+	switch {
+	case s.Slack != nil:
+		hooks, err := severityMap(path+".slack.severityHooks", s.Slack.SeverityHooks)
+		if err != nil {
+			return nil, err
+		}
+		return NewSlack(s.Slack.InfoHook, s.Slack.AlertHook, environment, testMode, WithSlackSeverityHooks(hooks)), nil
+
+	case s.SlackAPI != nil:
+		channels, err := severityMap(path+".slackAPI.severityChannels", s.SlackAPI.SeverityChannels)
+		if err != nil {
+			return nil, err
+		}
+		opts := []SlackAPIOption{WithSlackAPISeverityChannels(channels)}
+		if s.SlackAPI.URL != "" {
+			opts = append(opts, WithSlackAPIURL(s.SlackAPI.URL))
+		}
+		if b.opts.handleErr != nil {
+			opts = append(opts, WithSlackAPIErrorHandler(b.opts.handleErr))
+		}
+		return NewSlackAPI(s.SlackAPI.Token, s.SlackAPI.InfoChannel, s.SlackAPI.AlertChannel, environment, testMode, opts...), nil
+
+	case s.Teams != nil:
+		hooks, err := severityMap(path+".teams.severityHooks", s.Teams.SeverityHooks)
+		if err != nil {
+			return nil, err
+		}
+		return NewTeams(s.Teams.InfoHook, s.Teams.AlertHook, environment, testMode, WithTeamsSeverityHooks(hooks)), nil
+
+	case s.Webhook != nil:
+		cfg := WebhookConfig{
+			URL:         s.Webhook.URL,
+			Headers:     s.Webhook.Headers,
+			Template:    s.Webhook.Template,
+			ContentType: s.Webhook.ContentType,
+			Secret:      s.Webhook.Secret,
+			Environment: environment,
+		}
+		urls, err := severityMap(path+".webhook.severityURLs", s.Webhook.SeverityURLs)
+		if err != nil {
+			return nil, err
+		}
+		cfg.SeverityURLs = urls
+		webhook, err := NewWebhook(cfg)
+		if err != nil {
+			return nil, &ConfigError{Path: path + ".webhook", Err: err}
+		}
+		return webhook, nil
+
+	case s.PagerDuty != nil:
+		var opts []PagerDutyOption
+		if s.PagerDuty.EventsURL != "" {
+			opts = append(opts, WithPagerDutyEventsURL(s.PagerDuty.EventsURL))
+		}
+		return NewPagerDuty(s.PagerDuty.RoutingKey, opts...), nil
+	}
+
+	es := s.Email
+	cfg := EmailConfig{
+		Addr:        es.Addr,
+		From:        es.From,
+		To:          es.To,
+		Username:    es.Username,
+		Password:    es.Password,
+		RequireTLS:  es.RequireTLS,
+		Environment: environment,
+		BatchSize:   es.BatchSize,
+	}
+	if len(es.SeverityTo) > 0 {
+		cfg.SeverityTo = map[Severity][]string{}
+	}
+	for name, to := range es.SeverityTo {
+		severity, err := ParseSeverity(name)
+		if err != nil {
+			return nil, &ConfigError{Path: fmt.Sprintf("%s.email.severityTo[%s]", path, name), Err: err}
+		}
+		cfg.SeverityTo[severity] = to
+	}
+	var err error
+	if cfg.BatchInterval, err = parseDuration(path+".email.batchInterval", es.BatchInterval); err != nil {
+		return nil, err
+	}
+	if cfg.Timeout, err = parseDuration(path+".email.timeout", es.Timeout); err != nil {
+		return nil, err
+	}
+	var opts []EmailOption
+	if b.opts.handleErr != nil {
+		opts = append(opts, WithEmailErrorHandler(b.opts.handleErr))
+	}
+	return NewEmail(cfg, opts...), nil
+}
+
+// lookup returns the senders with the names
+func (b *configBuilder) lookup(path string, names []string) ([]Sender, error) {
+	senders := make([]Sender, 0, len(names))
+	for i, name := range names {
+		sender, ok := b.n.senders[name]
+		if !ok {
+			return nil, &ConfigError{Path: fmt.Sprintf("%s[%d]", path, i), Err: fmt.Errorf("unknown sender %q", name)}
+		}
+		senders = append(senders, sender)
+	}
+	return senders, nil
+}
+
+func (b *configBuilder) silence(path string, ss SilenceSettings) error {
+	match, err := parseMatch(path+".match", ss.Match)
+	if err != nil {
+		return err
+	}
+	silence := Silence{Match: match, CreatedBy: ss.CreatedBy, Comment: ss.Comment}
+	if ss.StartsAt != "" {
+		if silence.StartsAt, err = time.Parse(time.RFC3339, ss.StartsAt); err != nil {
+			return &ConfigError{Path: path + ".startsAt", Err: errors.New("must be an RFC 3339 time")}
+		}
+	}
+	if silence.EndsAt, err = time.Parse(time.RFC3339, ss.EndsAt); err != nil {
+		return &ConfigError{Path: path + ".endsAt", Err: errors.New("must be an RFC 3339 time")}
+	}
+	if !silence.EndsAt.After(b.opts.clock.Now()) {
+		return nil
+	}
+
+	if _, err := b.n.silencer.Add(silence); err != nil {
+		return &ConfigError{Path: path, Err: err}
+	}
+	return nil
+}
+
+func (b *configBuilder) dispatcherOptions(path string, as *AsyncSettings) ([]DispatcherOption, error) {
+	var opts []DispatcherOption
+	if as.QueueSize > 0 {
+		opts = append(opts, WithQueueSize(as.QueueSize))
+	}
+	if as.Workers > 0 {
+		opts = append(opts, WithWorkers(as.Workers))
+	}
+
+	maxAttempts, baseDelay, maxDelay := 5, time.Second, 30*time.Second
+	if as.MaxAttempts > 0 {
+		maxAttempts = as.MaxAttempts
+	}
+	if d, err := parseDuration(path+".baseDelay", as.BaseDelay); err != nil {
+		return nil, err
+	} else if d > 0 {
+		baseDelay = d
+	}
+	if d, err := parseDuration(path+".maxDelay", as.MaxDelay); err != nil {
+		return nil, err
+	} else if d > 0 {
+		maxDelay = d
+	}
+	opts = append(opts, WithRetries(maxAttempts, baseDelay, maxDelay))
+
+	switch as.DropPolicy {
+	case "dropOldest":
+		opts = append(opts, WithDropPolicy(DropOldest))
+	case "block":
+		opts = append(opts, WithDropPolicy(Block))
+	}
+	if d, err := parseDuration(path+".sendTimeout", as.SendTimeout); err != nil {
+		return nil, err
+	} else if d > 0 {
+		opts = append(opts, WithSendTimeout(d))
+	}
+	if b.opts.handleErr != nil {
+		opts = append(opts, WithDeliveryErrorHandler(b.opts.handleErr))
+	}
+	return opts, nil
+}
+
+func parseMatch(path string, ms MatchSettings) (Match, error) {
+	m := Match{
+		Labels:       ms.Labels,
+		Sources:      ms.Sources,
+		Methods:      ms.Methods,
+		Environments: ms.Environments,
+	}
+	if ms.MinSeverity != "" {
+		severity, err := ParseSeverity(ms.MinSeverity)
+		if err != nil {
+			return Match{}, &ConfigError{Path: path + ".minSeverity", Err: err}
+		}
+		m.MinSeverity = severity
+	}
+	for i, name := range ms.Severities {
+		severity, err := ParseSeverity(name)
+		if err != nil {
+			return Match{}, &ConfigError{Path: fmt.Sprintf("%s.severities[%d]", path, i), Err: err}
+		}
+		m.Severities = append(m.Severities, severity)
+	}
+	return m, nil
+}
+
+// severityMap parses the severity names of the keys, nil is returned for an empty map
+func severityMap(path string, m map[string]string) (map[Severity]string, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	parsed := make(map[Severity]string, len(m))
+	for name, value := range m {
+		severity, err := ParseSeverity(name)
+		if err != nil {
+			return nil, &ConfigError{Path: fmt.Sprintf("%s[%s]", path, name), Err: err}
+		}
+		parsed[severity] = value
+	}
+	return parsed, nil
+}
+
+// parseDuration parses a duration of the config, an empty string is zero
+func parseDuration(path, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, &ConfigError{Path: path, Err: fmt.Errorf("must be a duration like \"5m\", got %q", s)}
+	}
+	return d, nil
+}
+
+func jsonName(f reflect.StructField) string {
+	name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
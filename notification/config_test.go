@@ -0,0 +1,304 @@
+package notification_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+// hookServer records the paths and bodies of the requests it receives
+type hookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+}
+
+func newHookServer() *hookServer {
+	s := &hookServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, r.URL.Path+" "+toString(body["title"]))
+	}))
+	return s
+}
+
+func (s *hookServer) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func toString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func Test_FromConfig(t *testing.T) {
+	server := newHookServer()
+	defer server.Close()
+
+	env := map[string]string{"HOOK_URL": server.URL}
+	cfg := `
+environment: prod
+source: orders
+senders:
+  ops:
+    webhook:
+      url: ${HOOK_URL}/ops
+      severityURLs:
+        alert: ${HOOK_URL}/ops-alerts
+  db:
+    webhook:
+      url: ${HOOK_URL}/db
+routes:
+  - match:
+      labels:
+        team: db
+    senders: [db]
+    stop: true
+default: [ops]
+enrich:
+  service: orders
+  links:
+    - text: Open logs
+      url: https://logs.example.com/?q={{query .RequestID}}
+dedup:
+  window: 1m
+async:
+  queueSize: 10
+  dropPolicy: block
+silences:
+  - match:
+      sources: [dbmigration]
+    endsAt: ` + time.Now().Add(time.Hour).Format(time.RFC3339) + `
+    createdBy: jane
+    comment: migrating
+`
+	n, err := notification.FromConfig([]byte(cfg), notification.WithConfigLookupEnv(func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	_ = n.Send(ctx, notification.Message{Severity: notification.SeverityInfo, Title: "deployed"})
+	_ = n.Send(ctx, notification.Message{Severity: notification.SeverityAlert, Title: "db down", Labels: map[string]string{"team": "db"}})
+	_ = n.Send(ctx, notification.Message{Severity: notification.SeverityAlert, Title: "db down", Labels: map[string]string{"team": "db"}})
+	_ = n.Send(ctx, notification.Message{Severity: notification.SeverityAlert, Title: "migration failed", Source: "dbmigration"})
+	_ = n.Send(ctx, notification.Message{Severity: notification.SeverityAlert, Title: "api down"})
+
+	if silences := n.Silencer().Silences(); len(silences) != 1 || silences[0].Comment != "migrating" {
+		t.Errorf("expected the silence of the config, got: %+v", silences)
+	}
+	if _, ok := n.Sender("ops"); !ok {
+		t.Errorf("expected the ops sender")
+	}
+	if err := n.Close(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the silenced message is dropped and the duplicate is summarized when the deduplicator is closed
+	got := strings.Join(server.Requests(), ", ")
+	if want := "/ops deployed, /db db down, /ops-alerts api down, /db db down"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func Test_FromConfig_Errors(t *testing.T) {
+	env := map[string]string{"HOOK_URL": "https://hooks.example.com/x"}
+	for name, test := range map[string]struct {
+		cfg  string
+		path string
+	}{
+		"no senders": {
+			cfg:  `environment: prod`,
+			path: "senders",
+		},
+		"missing variable": {
+			cfg:  "senders:\n  ops:\n    slack:\n      infohook: ${HOOK_URL}\n      alerthook: ${ALERT_URL}",
+			path: "senders[ops].slack.alerthook",
+		},
+		"invalid URL": {
+			cfg:  "senders:\n  ops:\n    slack:\n      infohook: ${HOOK_URL}\n      alerthook: not a url",
+			path: "senders[ops].slack.alerthook",
+		},
+		"no backend": {
+			cfg:  "senders:\n  ops: {}",
+			path: "senders[ops]",
+		},
+		"two backends": {
+			cfg:  "senders:\n  ops:\n    pagerDuty:\n      routingKey: key\n    webhook:\n      url: ${HOOK_URL}",
+			path: "senders[ops]",
+		},
+		"unknown sender": {
+			cfg:  "senders:\n  pd:\n    pagerDuty:\n      routingKey: key\nroutes:\n  - senders: [pd, ops]",
+			path: "routes[0].senders[1]",
+		},
+		"unknown severity": {
+			cfg:  "senders:\n  pd:\n    pagerDuty:\n      routingKey: key\nroutes:\n  - match:\n      minSeverity: urgent\n    senders: [pd]",
+			path: "routes[0].match.minSeverity",
+		},
+		"invalid duration": {
+			cfg:  "senders:\n  pd:\n    pagerDuty:\n      routingKey: key\ndedup:\n  window: 5 minutes",
+			path: "dedup.window",
+		},
+		"invalid drop policy": {
+			cfg:  "senders:\n  pd:\n    pagerDuty:\n      routingKey: key\nasync:\n  dropPolicy: never",
+			path: "async.dropPolicy",
+		},
+		"wrong type": {
+			cfg:  "senders:\n  pd:\n    pagerDuty:\n      routingKey: key\nasync:\n  queueSize: many",
+			path: "async.queueSize",
+		},
+		"invalid link template": {
+			cfg:  "senders:\n  pd:\n    pagerDuty:\n      routingKey: key\nenrich:\n  links:\n    - text: Open logs\n      url: '{{.RequestID'",
+			path: "enrich.links[0].url",
+		},
+		"vault without secrets": {
+			cfg:  "senders:\n  ops:\n    slack:\n      infohook: ${HOOK_URL}\n      alerthook: ${vault:alert-hook}",
+			path: "senders[ops].slack.alerthook",
+		},
+		"unknown field": {
+			cfg: "senders:\n  pd:\n    pagerDuty:\n      routingKey: key\n      url: x",
+		},
+	} {
+		_, err := notification.FromConfig([]byte(test.cfg), notification.WithConfigLookupEnv(func(name string) (string, bool) {
+			v, ok := env[name]
+			return v, ok
+		}))
+		var cfgErr *notification.ConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Errorf("%s: expected a ConfigError, got: %v", name, err)
+			continue
+		}
+		if cfgErr.Path != test.path {
+			t.Errorf("%s: expected the path %q, got: %v", name, test.path, err)
+		}
+	}
+}
+
+func Test_ParseConfig_Interpolation(t *testing.T) {
+	env := map[string]string{"KEY": "secret", "EMPTY": ""}
+	cfg, err := notification.ParseConfig([]byte(`{
+		"senders": {"pd": {"pagerDuty": {"routingKey": "${KEY}-$${KEY}-${EMPTY}-${EMPTY:-default}-${UNSET:-x}"}}}
+	}`), notification.WithConfigLookupEnv(func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key := cfg.Senders["pd"].PagerDuty.RoutingKey; key != "secret-${KEY}--default-x" {
+		t.Errorf("unexpected interpolation: %q", key)
+	}
+}
+
+func Test_FromConfig_Vault(t *testing.T) {
+	server := newHookServer()
+	defer server.Close()
+
+	vault := newFakeVault(map[string]string{"ops-hook": server.URL + "/v1", "routing-key": "key"})
+	clock := newFakeClock()
+	secrets := notification.NewSecrets(vault, notification.WithSecretsClock(clock), notification.WithSecretsRefresh(time.Minute))
+	defer secrets.Close(context.Background())
+
+	data := `
+senders:
+  ops:
+    webhook:
+      url: ${vault:ops-hook}
+      headers:
+        X-Team: ops
+  pd:
+    pagerDuty:
+      routingKey: ${vault:routing-key}
+default: [ops]
+`
+	cfg, err := notification.ParseConfig([]byte(data), notification.WithConfigSecrets(secrets))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rendered, err := cfg.Render()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(rendered), server.URL) || strings.Contains(string(rendered), "key\n") ||
+		strings.Count(string(rendered), notification.Redacted) != 2 || !strings.Contains(string(rendered), "X-Team: ops") {
+		t.Errorf("expected the secrets to be redacted, got:\n%s", rendered)
+	}
+
+	n, err := notification.FromConfig([]byte(data), notification.WithConfigSecrets(secrets))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer n.Close(context.Background())
+	ctx := context.Background()
+	_ = n.Send(ctx, notification.Message{Title: "deployed"})
+
+	// the webhook is built again with the rotated URL after the next refresh
+	vault.Set("ops-hook", server.URL+"/v2")
+	clock.Advance(time.Minute)
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		requests := server.Requests()
+		if requests[len(requests)-1] == "/v2 rotated" {
+			return
+		}
+		_ = n.Send(ctx, notification.Message{Title: "rotated"})
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("expected a message to the rotated URL, got: %v", server.Requests())
+}
+
+func Test_FromConfig_SilencesClock(t *testing.T) {
+	server := newHookServer()
+	defer server.Close()
+
+	clock := newFakeClock()
+	n, err := notification.FromConfig([]byte(`
+senders:
+  ops:
+    webhook:
+      url: `+server.URL+`/ops
+silences:
+  - match:
+      sources: [dbmigration]
+    startsAt: 2021-01-01T01:00:00Z
+    endsAt: 2021-01-01T02:00:00Z
+    createdBy: jane
+  - match:
+      sources: [api]
+    endsAt: 2020-12-31T00:00:00Z
+    createdBy: jane
+`), notification.WithConfigClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	defer n.Close(ctx)
+
+	if silences := n.Silencer().Silences(); len(silences) != 1 || silences[0].Match.Sources[0] != "dbmigration" {
+		t.Fatalf("expected only the silence that hasn't ended by the clock, got: %+v", silences)
+	}
+	msg := notification.Message{Severity: notification.SeverityAlert, Title: "migration failed", Source: "dbmigration"}
+	_ = n.Send(ctx, msg)
+	clock.Advance(90 * time.Minute)
+	_ = n.Send(ctx, msg)
+
+	if got := strings.Join(server.Requests(), ", "); got != "/ops migration failed" {
+		t.Errorf("expected the message in the silence to be dropped, got %q", got)
+	}
+}
@@ -0,0 +1,53 @@
+package notification
+
+import (
+	"context"
+	"time"
+)
+
+// DetachContext returns a context with the values of ctx but without its deadline and cancellation.
+// It is used to deliver notifications about a request after the request itself is done or cancelled.
+func DetachContext(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	traceIDKey
+)
+
+// ContextWithRequestID returns a context with the ID of the request it belongs to, see Enricher
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request ID set with ContextWithRequestID, or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// ContextWithTraceID returns a context with the ID of the trace it belongs to, see Enricher
+func ContextWithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey, id)
+}
+
+// TraceIDFromContext returns the trace ID set with ContextWithTraceID, or an empty string
+func TraceIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey).(string)
+	return id
+}
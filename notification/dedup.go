@@ -0,0 +1,212 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+type DedupOption func(*Deduplicator)
+
+// WithDedupWindow sets how long repeats of a message are suppressed after it was sent, the default is 5 minutes
+func WithDedupWindow(d time.Duration) DedupOption {
+	return func(dd *Deduplicator) {
+		dd.window = d
+	}
+}
+
+// WithFingerprint sets how messages are identified, the default is DefaultFingerprint
+func WithFingerprint(f FingerprintFunc) DedupOption {
+	return func(dd *Deduplicator) {
+		dd.fingerprint = f
+	}
+}
+
+// WithDedupClock replaces the system clock, used in tests
+func WithDedupClock(c Clock) DedupOption {
+	return func(dd *Deduplicator) {
+		dd.clock = c
+	}
+}
+
+// WithSummaryErrorHandler sets the function that is called when a summary could not be sent.
+// The default handler logs the error with the standard logger.
+func WithSummaryErrorHandler(f DeliveryErrorHandler) DedupOption {
+	return func(dd *Deduplicator) {
+		dd.handleErr = f
+	}
+}
+
+// Deduplicator is a Sender that sends the first occurrence of a message immediately and suppresses
+// repeats of it within a window. When the window closes a summary with the number of occurrences is sent,
+// if there were any repeats. A Resolved message is never suppressed, it closes the window of its fingerprint
+// and is sent right after the summary.
+type Deduplicator struct {
+	next        Sender
+	window      time.Duration
+	fingerprint FingerprintFunc
+	clock       Clock
+	handleErr   DeliveryErrorHandler
+
+	mu      sync.Mutex
+	windows map[string]*dedupWindow
+	closed  chan struct{}
+	wg      sync.WaitGroup
+}
+
+type dedupWindow struct {
+	ctx        context.Context
+	last       Message
+	suppressed int
+	// resolved is closed when a Resolved message closes the window before it expires
+	resolved chan struct{}
+}
+
+// NewDeduplicator returns a Deduplicator sending to next
+func NewDeduplicator(next Sender, opts ...DedupOption) *Deduplicator {
+	d := &Deduplicator{
+		next:        next,
+		window:      5 * time.Minute,
+		fingerprint: DefaultFingerprint,
+		clock:       SystemClock(),
+		handleErr: func(msg Message, err error) {
+			log.Printf("notification: could not send summary of %q: %v", msg.Title, err)
+		},
+		windows: map[string]*dedupWindow{},
+		closed:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Send sends the message unless it is a repeat of a message sent within the window
+func (d *Deduplicator) Send(ctx context.Context, msg Message) error {
+	fp := d.fingerprint(msg)
+	if msg.Resolved {
+		return d.resolve(ctx, fp, msg)
+	}
+
+	d.mu.Lock()
+	if w, ok := d.windows[fp]; ok {
+		w.suppressed++
+		w.last = msg
+		d.mu.Unlock()
+		return nil
+	}
+
+	select {
+	case <-d.closed:
+		// no more windows are opened after close, the message is sent as it is
+		d.mu.Unlock()
+		return d.next.Send(ctx, msg)
+	default:
+	}
+
+	w := &dedupWindow{ctx: DetachContext(ctx), last: msg, resolved: make(chan struct{})}
+	d.windows[fp] = w
+	expired := d.clock.After(d.window)
+	d.wg.Add(1)
+	d.mu.Unlock()
+
+	go func() {
+		defer d.wg.Done()
+		select {
+		case <-expired:
+		case <-d.closed:
+		case <-w.resolved:
+			return
+		}
+		d.closeWindow(fp, w)
+	}()
+
+	return d.next.Send(ctx, msg)
+}
+
+// Close closes all windows, sending their summaries
+func (d *Deduplicator) Close(ctx context.Context) error {
+	d.mu.Lock()
+	select {
+	case <-d.closed:
+	default:
+		close(d.closed)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// resolve closes the window of the fingerprint and sends its summary before the resolved message,
+// so that the repeats are reported as part of the problem and not after it was resolved
+func (d *Deduplicator) resolve(ctx context.Context, fp string, msg Message) error {
+	d.mu.Lock()
+	w, ok := d.windows[fp]
+	if ok {
+		delete(d.windows, fp)
+		close(w.resolved)
+	}
+	d.mu.Unlock()
+
+	if ok {
+		d.sendSummary(w)
+	}
+	return d.next.Send(ctx, msg)
+}
+
+func (d *Deduplicator) closeWindow(fp string, w *dedupWindow) {
+	d.mu.Lock()
+	if d.windows[fp] != w {
+		// the window was closed by a resolved message
+		d.mu.Unlock()
+		return
+	}
+	delete(d.windows, fp)
+	d.mu.Unlock()
+
+	d.sendSummary(w)
+}
+
+func (d *Deduplicator) sendSummary(w *dedupWindow) {
+	if w.suppressed == 0 {
+		return
+	}
+
+	summary := w.last
+	occurrences := w.suppressed + 1
+	summary.Body = fmt.Sprintf("Occurred %d times in the last %s", occurrences, formatDuration(d.window))
+	if w.last.Body != "" {
+		summary.Body += "\n" + w.last.Body
+	}
+	summary.Timestamp = d.clock.Now()
+
+	if err := d.next.Send(w.ctx, summary); err != nil {
+		d.handleErr(summary, err)
+	}
+}
+
+// formatDuration formats a duration without trailing zero units, e.g. 5m instead of 5m0s
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
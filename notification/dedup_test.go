@@ -0,0 +1,118 @@
+package notification_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+func Test_Deduplicator(t *testing.T) {
+	sent := make(chan notification.Message, 10)
+	recorder := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		sent <- msg
+		return nil
+	})
+
+	clock := newFakeClock()
+	d := notification.NewDeduplicator(recorder, notification.WithDedupWindow(5*time.Minute), notification.WithDedupClock(clock))
+
+	msg := func(id string) notification.Message {
+		return notification.Message{
+			Severity: notification.SeverityAlert,
+			Title:    "Error occurred in a call to Get",
+			Err:      errors.New("could not find thing " + id),
+			Labels:   map[string]string{notification.LabelMethod: "Get"},
+		}
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		if err := d.Send(context.Background(), msg(id)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	_ = d.Send(context.Background(), notification.Message{Title: "other"})
+
+	for _, want := range []string{"Error occurred in a call to Get", "other"} {
+		if got := <-sent; got.Title != want {
+			t.Errorf("expected %q to be sent, got: %q", want, got.Title)
+		}
+	}
+	select {
+	case got := <-sent:
+		t.Fatalf("expected repeats to be suppressed, got: %+v", got)
+	default:
+	}
+
+	clock.Advance(5 * time.Minute)
+	select {
+	case got := <-sent:
+		if !strings.HasPrefix(got.Body, "Occurred 3 times in the last 5m") {
+			t.Errorf("unexpected summary: %q", got.Body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a summary when the window closed")
+	}
+
+	_ = d.Close(context.Background())
+	select {
+	case got := <-sent:
+		t.Errorf("expected no summary for a message without repeats, got: %+v", got)
+	default:
+	}
+}
+
+func Test_NormalizeError(t *testing.T) {
+	a := notification.NormalizeError("thing 4ae71336-e44b-39bf-b9d2-752e234818a5 not found at 10.0.0.1:5432")
+	b := notification.NormalizeError("thing 9f1c2d3e-0000-4bbb-8ccc-123456789abc not found at 10.0.0.2:5432")
+	if a != b {
+		t.Errorf("expected equal normalized errors, got: %q and %q", a, b)
+	}
+}
+
+func Test_Deduplicator_ResolvedPassesThrough(t *testing.T) {
+	var events []notification.PagerDutyEvent
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notification.PagerDutyEvent
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer stub.Close()
+
+	clock := newFakeClock()
+	pd := notification.NewPagerDuty("routing-key", notification.WithPagerDutyEventsURL(stub.URL))
+	d := notification.NewDeduplicator(pd, notification.WithDedupWindow(5*time.Minute), notification.WithDedupClock(clock))
+
+	failure := notification.Message{Severity: notification.SeverityAlert, Title: "Error occurred in a call to Get", Fingerprint: "Get"}
+	recovery := notification.Message{Severity: notification.SeverityInfo, Title: "Get recovered", Fingerprint: "Get", Resolved: true}
+	for _, msg := range []notification.Message{failure, failure, failure, recovery} {
+		if err := d.Send(context.Background(), msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// the resolve is sent right away, after the summary of the repeats
+	if len(events) != 3 {
+		t.Fatalf("expected the trigger, the summary and the resolve, got: %+v", events)
+	}
+	if summary := events[1]; summary.EventAction != "trigger" || summary.Payload.Summary != failure.Title ||
+		!strings.Contains(fmt.Sprint(summary.Payload.CustomDetails), "Occurred 3 times") {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if events[2].EventAction != "resolve" || events[2].DedupKey != events[0].DedupKey {
+		t.Errorf("unexpected resolve event: %+v", events[2])
+	}
+
+	clock.Advance(5 * time.Minute)
+	_ = d.Close(context.Background())
+	if len(events) != 3 {
+		t.Errorf("expected nothing to be sent when the resolved window expires, got: %+v", events[3:])
+	}
+}
@@ -0,0 +1,244 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type DigestOption func(*Digest)
+
+// WithDigestInterval sets how often the digest is sent, the default is hourly. The digests are sent at multiples of
+// the interval since the zero time, so time.Hour sends them on the hour and 24*time.Hour at midnight UTC.
+func WithDigestInterval(d time.Duration) DigestOption {
+	return func(dg *Digest) {
+		dg.interval = d
+	}
+}
+
+// WithDigestSamples sets how many bodies of each group are shown in the digest, the default is 3
+func WithDigestSamples(n int) DigestOption {
+	return func(dg *Digest) {
+		dg.samples = n
+	}
+}
+
+// WithDigestMaxGroups sets how many groups are listed in the digest, the default is 20.
+// The groups with the most messages are listed, the rest are counted.
+func WithDigestMaxGroups(n int) DigestOption {
+	return func(dg *Digest) {
+		dg.maxGroups = n
+	}
+}
+
+// WithDigestSource sets the Source of the digest messages, e.g. the name of the service
+func WithDigestSource(source string) DigestOption {
+	return func(dg *Digest) {
+		dg.source = source
+	}
+}
+
+// WithDigestClock replaces the system clock, used in tests
+func WithDigestClock(c Clock) DigestOption {
+	return func(dg *Digest) {
+		dg.clock = c
+	}
+}
+
+// WithDigestErrorHandler sets the function that is called when a digest could not be sent.
+// The default handler logs the error with the standard logger.
+func WithDigestErrorHandler(f DeliveryErrorHandler) DigestOption {
+	return func(dg *Digest) {
+		dg.handleErr = f
+	}
+}
+
+// Digest is a Sender that collects debug, info and notice messages and sends them as one summary per interval, grouped
+// by source and title with their counts and a sample of their bodies. Warnings, alerts and Resolved messages are
+// sent immediately.
+type Digest struct {
+	next      Sender
+	interval  time.Duration
+	samples   int
+	maxGroups int
+	source    string
+	clock     Clock
+	handleErr DeliveryErrorHandler
+
+	mu     sync.Mutex
+	groups map[digestKey]*digestGroup
+	count  int
+	since  time.Time
+	closed chan struct{}
+	done   chan struct{}
+}
+
+type digestKey struct {
+	source, title string
+}
+
+type digestGroup struct {
+	digestKey
+	count   int
+	samples []string
+	// first orders groups with the same count by when they were first seen
+	first int
+}
+
+// NewDigest starts a Digest sending to next
+func NewDigest(next Sender, opts ...DigestOption) *Digest {
+	d := &Digest{
+		next:      next,
+		interval:  time.Hour,
+		samples:   3,
+		maxGroups: 20,
+		clock:     SystemClock(),
+		handleErr: func(msg Message, err error) {
+			log.Printf("notification: could not send digest %q: %v", msg.Title, err)
+		},
+		groups: map[digestKey]*digestGroup{},
+		closed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.since = d.clock.Now()
+
+	go d.run(d.nextDigest())
+	return d
+}
+
+// Send adds a message below SeverityWarning to the digest, other messages and Resolved messages are sent immediately
+func (d *Digest) Send(ctx context.Context, msg Message) error {
+	if msg.Severity >= SeverityWarning || msg.Resolved {
+		return d.next.Send(ctx, msg)
+	}
+
+	d.mu.Lock()
+	select {
+	case <-d.closed:
+		// the lock isn't held while sending, it would block the other messages and the digest
+		d.mu.Unlock()
+		return d.next.Send(ctx, msg)
+	default:
+	}
+	defer d.mu.Unlock()
+
+	key := digestKey{source: msg.Source, title: msg.Title}
+	g, ok := d.groups[key]
+	if !ok {
+		g = &digestGroup{digestKey: key, first: len(d.groups)}
+		d.groups[key] = g
+	}
+	g.count++
+	d.count++
+	if sample := msg.Body; sample != "" && len(g.samples) < d.samples {
+		g.samples = append(g.samples, truncate(sample, 200))
+	}
+	return nil
+}
+
+// Close sends the collected messages and stops the digest, messages sent after Close are sent immediately
+func (d *Digest) Close(ctx context.Context) error {
+	d.mu.Lock()
+	select {
+	case <-d.closed:
+	default:
+		close(d.closed)
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if msg, ok := d.take(); ok {
+		return d.next.Send(ctx, msg)
+	}
+	return nil
+}
+
+func (d *Digest) run(next <-chan time.Time) {
+	defer close(d.done)
+	for {
+		select {
+		case <-next:
+		case <-d.closed:
+			return
+		}
+
+		// the next digest is scheduled before this one is sent, so that a clock that is advanced after
+		// a digest was sent always triggers it
+		next = d.nextDigest()
+		if msg, ok := d.take(); ok {
+			if err := d.next.Send(context.Background(), msg); err != nil {
+				d.handleErr(msg, err)
+			}
+		}
+	}
+}
+
+func (d *Digest) nextDigest() <-chan time.Time {
+	now := d.clock.Now()
+	return d.clock.After(now.Truncate(d.interval).Add(d.interval).Sub(now))
+}
+
+// take returns the digest of the collected messages and starts a new one, false if there is nothing to send
+func (d *Digest) take() (Message, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.clock.Now()
+	groups, count, since := d.groups, d.count, d.since
+	d.groups, d.count, d.since = map[digestKey]*digestGroup{}, 0, now
+	if count == 0 {
+		return Message{}, false
+	}
+
+	sorted := make([]*digestGroup, 0, len(groups))
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].count != sorted[j].count {
+			return sorted[i].count > sorted[j].count
+		}
+		return sorted[i].first < sorted[j].first
+	})
+
+	var lines []string
+	for i, g := range sorted {
+		if i == d.maxGroups {
+			rest := 0
+			for _, g := range sorted[i:] {
+				rest += g.count
+			}
+			lines = append(lines, fmt.Sprintf("_and %d more groups with %d messages_", len(sorted)-i, rest))
+			break
+		}
+
+		line := fmt.Sprintf("*%d×* %s", g.count, g.title)
+		if g.source != "" {
+			line += fmt.Sprintf(" (%s)", g.source)
+		}
+		lines = append(lines, line)
+		for _, s := range g.samples {
+			lines = append(lines, "> "+strings.ReplaceAll(s, "\n", " "))
+		}
+	}
+
+	return Message{
+		Severity:  SeverityInfo,
+		Title:     fmt.Sprintf("Digest of %d notifications in the last %s", count, formatDuration(now.Sub(since).Round(time.Minute))),
+		Body:      strings.Join(lines, "\n"),
+		Source:    d.source,
+		Timestamp: now,
+	}, true
+}
@@ -0,0 +1,95 @@
+package notification_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+	"github.com/SecuritasCrimePrediction/apitools-go/notification/notificationtest"
+)
+
+func Test_Digest(t *testing.T) {
+	recorder := notificationtest.NewRecorder()
+	clock := newFakeClock()
+	digest := notification.NewDigest(recorder,
+		notification.WithDigestClock(clock),
+		notification.WithDigestSamples(2),
+		notification.WithDigestMaxGroups(2))
+
+	ctx := context.Background()
+	clock.Advance(30 * time.Minute)
+	for i := 0; i < 5; i++ {
+		_ = digest.Send(ctx, notification.Message{Title: "Call to CreateUser succeeded", Source: "users", Body: fmt.Sprintf("user %d", i)})
+	}
+	_ = digest.Send(ctx, notification.Message{Title: "Call to DeleteUser succeeded", Source: "users"})
+	_ = digest.Send(ctx, notification.Message{Title: "Call to DeleteUser succeeded", Source: "users"})
+	_ = digest.Send(ctx, notification.Message{Title: "Export finished"})
+	if err := digest.Send(ctx, notification.Message{Severity: notification.SeverityAlert, Title: "db down"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msgs := recorder.Messages(); len(msgs) != 1 || msgs[0].Title != "db down" {
+		t.Fatalf("expected only the alert to be sent immediately, got: %v", msgs)
+	}
+
+	// the digest is sent on the hour
+	clock.Advance(30 * time.Minute)
+	got, ok := recorder.WaitForMessage(time.Second, notificationtest.WithSeverity(notification.SeverityInfo))
+	if !ok {
+		t.Fatalf("expected a digest")
+	}
+	want := "*5×* Call to CreateUser succeeded (users)\n> user 0\n> user 1\n*2×* Call to DeleteUser succeeded (users)\n_and 1 more groups with 1 messages_"
+	if got.Title != "Digest of 8 notifications in the last 1h" || got.Body != want {
+		t.Errorf("unexpected digest: %q\n%s", got.Title, got.Body)
+	}
+
+	// an empty digest isn't sent, the collected messages are sent on close
+	clock.Advance(time.Hour)
+	_ = digest.Send(ctx, notification.Message{Title: "Export finished"})
+	if err := digest.Close(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msgs := recorder.Messages()
+	if len(msgs) != 3 || msgs[2].Title != "Digest of 1 notifications in the last 1h" {
+		t.Errorf("expected the pending digest on close, got: %v", msgs)
+	}
+}
+
+func Test_Digest_SendAfterClose(t *testing.T) {
+	recorder := notificationtest.NewRecorder()
+	sending := make(chan struct{})
+	release := make(chan struct{})
+	slow := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		if msg.Title == "slow" {
+			close(sending)
+			<-release
+		}
+		return recorder.Send(ctx, msg)
+	})
+	digest := notification.NewDigest(slow)
+	ctx := context.Background()
+	if err := digest.Close(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	go func() {
+		_ = digest.Send(ctx, notification.Message{Title: "slow"})
+	}()
+	<-sending
+	// a message after close doesn't wait for the one being sent
+	sent := make(chan struct{})
+	go func() {
+		_ = digest.Send(ctx, notification.Message{Title: "fast"})
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Errorf("expected the message to be sent while another one is being sent")
+	}
+	close(release)
+	if _, ok := recorder.WaitForMessage(time.Second, notificationtest.WithTitle("slow")); !ok {
+		t.Errorf("expected the slow message to be sent")
+	}
+}
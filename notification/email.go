@@ -0,0 +1,374 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSMTPTimeout limits dialing and talking to the SMTP server unless EmailConfig.Timeout is set
+const DefaultSMTPTimeout = 30 * time.Second
+
+// EmailConfig configures an Email sender
+type EmailConfig struct {
+	// Addr is the host:port of the SMTP server
+	Addr string
+	From string
+	// To receives the messages of the severities that are not in SeverityTo
+	To         []string
+	SeverityTo map[Severity][]string
+	// Username and Password enable PLAIN authentication
+	Username string
+	Password string
+	// RequireTLS fails sending if the server doesn't support STARTTLS, otherwise STARTTLS is used when available
+	RequireTLS bool
+	TLSConfig  *tls.Config
+	// Environment is shown in every email and in the subject
+	Environment string
+	// BatchInterval enables batching, messages are collected and sent as one email per recipient list
+	// when BatchSize messages are collected or when BatchInterval has passed since the first one
+	BatchInterval time.Duration
+	BatchSize     int
+	// Timeout limits dialing and sending an email, the default is DefaultSMTPTimeout
+	Timeout time.Duration
+}
+
+// Email sends messages as multipart HTML and plain text emails over SMTP
+type Email struct {
+	cfg       EmailConfig
+	handleErr DeliveryErrorHandler
+
+	mu      sync.Mutex
+	batches map[string]*emailBatch
+	wg      sync.WaitGroup
+}
+
+type emailBatch struct {
+	to       []string
+	messages []Message
+	timer    *time.Timer
+}
+
+type EmailOption func(*Email)
+
+// WithEmailErrorHandler sets the function that is called for each message of a batch that could not be sent
+// in the background. The default handler logs the error with the standard logger.
+func WithEmailErrorHandler(f DeliveryErrorHandler) EmailOption {
+	return func(e *Email) {
+		e.handleErr = f
+	}
+}
+
+// NewEmail returns an Email sender
+func NewEmail(cfg EmailConfig, opts ...EmailOption) *Email {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultSMTPTimeout
+	}
+	e := &Email{
+		cfg:     cfg,
+		batches: map[string]*emailBatch{},
+		handleErr: func(msg Message, err error) {
+			log.Printf("notification: could not email %q: %v", msg.Title, err)
+		},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Send emails the message to the recipients of its severity, or adds it to their batch if batching is enabled.
+// Errors from sending batches in the background go to the error handler, see WithEmailErrorHandler.
+// Close sends the pending batches and returns their errors.
+func (e *Email) Send(ctx context.Context, msg Message) error {
+	to, ok := e.cfg.SeverityTo[msg.Severity]
+	if !ok {
+		to = e.cfg.To
+	}
+	if len(to) == 0 {
+		return nil
+	}
+
+	if e.cfg.BatchInterval <= 0 {
+		return e.send(ctx, to, []Message{msg})
+	}
+
+	key := strings.Join(to, ",")
+
+	e.mu.Lock()
+	batch, ok := e.batches[key]
+	if !ok {
+		batch = &emailBatch{to: to}
+		batch.timer = time.AfterFunc(e.cfg.BatchInterval, func() {
+			if err := e.flush(context.Background(), key, batch); err != nil {
+				for _, msg := range batch.messages {
+					e.handleErr(msg, err)
+				}
+			}
+		})
+		e.batches[key] = batch
+	}
+	batch.messages = append(batch.messages, msg)
+	full := len(batch.messages) >= e.cfg.BatchSize
+	e.mu.Unlock()
+
+	if full {
+		return e.flush(ctx, key, batch)
+	}
+	return nil
+}
+
+// Close sends the pending batches and waits for the batches that are being sent in the background.
+// If ctx is done first the context error is returned.
+func (e *Email) Close(ctx context.Context) error {
+	type pending struct {
+		key   string
+		batch *emailBatch
+	}
+	e.mu.Lock()
+	batches := make([]pending, 0, len(e.batches))
+	for key, batch := range e.batches {
+		batches = append(batches, pending{key, batch})
+	}
+	e.mu.Unlock()
+
+	var errs []string
+	for _, p := range batches {
+		if err := e.flush(ctx, p.key, p.batch); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, ctx.Err().Error())
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// flush sends the batch if it is still pending
+func (e *Email) flush(ctx context.Context, key string, batch *emailBatch) error {
+	e.mu.Lock()
+	if e.batches[key] != batch {
+		e.mu.Unlock()
+		return nil
+	}
+	delete(e.batches, key)
+	batch.timer.Stop()
+	e.wg.Add(1)
+	e.mu.Unlock()
+
+	defer e.wg.Done()
+	return e.send(ctx, batch.to, batch.messages)
+}
+
+func (e *Email) send(ctx context.Context, to []string, messages []Message) error {
+	body, err := e.render(to, messages)
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(e.cfg.Addr)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(e.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	d := net.Dialer{Deadline: deadline}
+	conn, err := d.DialContext(ctx, "tcp", e.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		tlsConfig := e.cfg.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: host}
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	} else if e.cfg.RequireTLS {
+		return errors.New("smtp server does not support STARTTLS")
+	}
+
+	if e.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(e.cfg.From); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// render creates a multipart/alternative email with a plain text and an HTML part
+func (e *Email) render(to []string, messages []Message) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	header := []string{
+		"From: " + e.cfg.From,
+		"To: " + strings.Join(to, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", e.subject(messages)),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + mw.Boundary(),
+	}
+	body := bytes.NewBufferString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	var text bytes.Buffer
+	for i, msg := range messages {
+		if i > 0 {
+			text.WriteString("\n----\n\n")
+		}
+		fmt.Fprintf(&text, "[%s] %s\n", strings.ToUpper(msg.Severity.String()), msg.Title)
+		if t := msg.Text(); t != "" {
+			fmt.Fprintf(&text, "\n%s\n", t)
+		}
+	}
+	fmt.Fprintf(&text, "\nEnvironment: %s\n", e.cfg.Environment)
+
+	var html bytes.Buffer
+	if err := emailTemplate.Execute(&html, struct {
+		Messages    []Message
+		Environment string
+	}{messages, e.cfg.Environment}); err != nil {
+		return nil, err
+	}
+
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	body.Write(buf.Bytes())
+	return body.Bytes(), nil
+}
+
+func (e *Email) subject(messages []Message) string {
+	subject := messages[0].Title
+	if len(messages) > 1 {
+		subject = fmt.Sprintf("%d notifications", len(messages))
+	}
+
+	severity := messages[0].Severity
+	for _, msg := range messages {
+		if msg.Severity > severity {
+			severity = msg.Severity
+		}
+	}
+
+	return fmt.Sprintf("[%s] [%s] %s", e.cfg.Environment, strings.ToUpper(severity.String()), subject)
+}
+
+var emailTemplate = htmltemplate.Must(htmltemplate.New("email").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+{{- range .Messages}}
+<div style="border-left: 4px solid {{.Severity.Color}}; padding-left: 12px; margin-bottom: 24px;">
+<h2 style="margin: 0 0 8px 0;">{{.Title}}</h2>
+{{- if .Body}}
+<p style="white-space: pre-wrap;">{{.Body}}</p>
+{{- end}}
+{{- if or .Fields .Err .Source}}
+<table style="border-collapse: collapse;">
+{{- range .Fields}}
+<tr><th style="text-align: left; padding-right: 12px;">{{.Key}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+{{- if .Err}}
+<tr><th style="text-align: left; padding-right: 12px;">Error</th><td><code>{{.Err}}</code></td></tr>
+{{- end}}
+{{- if .Source}}
+<tr><th style="text-align: left; padding-right: 12px;">Source</th><td>{{.Source}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Links}}
+<p>{{range $i, $l := .Links}}{{if $i}} | {{end}}<a href="{{$l.URL}}">{{$l.Text}}</a>{{end}}</p>
+{{- end}}
+</div>
+{{- end}}
+<hr>
+<p>Environment: <b>{{.Environment}}</b></p>
+</body>
+</html>
+`))
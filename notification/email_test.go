@@ -0,0 +1,252 @@
+package notification_test
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+type receivedMail struct {
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTPServer is an in-process SMTP server that accepts all mail
+type fakeSMTPServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	mails    []receivedMail
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	s := &fakeSMTPServer{listener: l}
+	go s.serve()
+	t.Cleanup(func() { _ = l.Close() })
+	return s
+}
+
+func (s *fakeSMTPServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeSMTPServer) Mails() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMail(nil), s.mails...)
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+	var current receivedMail
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		switch upper := strings.ToUpper(cmd); {
+		case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(upper, "MAIL FROM:"):
+			current = receivedMail{from: strings.Trim(cmd[len("MAIL FROM:"):], "<>")}
+			reply("250 OK")
+		case strings.HasPrefix(upper, "RCPT TO:"):
+			current.to = append(current.to, strings.Trim(cmd[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case upper == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			current.data = data.String()
+			s.mu.Lock()
+			s.mails = append(s.mails, current)
+			s.mu.Unlock()
+			reply("250 OK")
+		case upper == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// parts returns the decoded parts of a multipart email by content type
+func parts(t *testing.T, data string) (string, map[string]string) {
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("could not read mail: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("could not parse content type: %v", err)
+	}
+	result := map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		content, _ := ioutil.ReadAll(quotedprintable.NewReader(p))
+		mediaType, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		result[mediaType] = string(content)
+	}
+	return subject, result
+}
+
+func Test_Email(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	email := notification.NewEmail(notification.EmailConfig{
+		Addr:        server.Addr(),
+		From:        "alerts@example.com",
+		To:          []string{"info@example.com"},
+		SeverityTo:  map[notification.Severity][]string{notification.SeverityAlert: {"oncall@example.com", "lead@example.com"}},
+		Environment: "prod",
+	})
+
+	err := email.Send(context.Background(), notification.Message{
+		Severity: notification.SeverityAlert,
+		Title:    "Certificate expires in 3 days",
+		Body:     "Renew <it>",
+		Fields:   []notification.Field{{Key: "Certificate", Value: "api-tls"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mails := server.Mails()
+	if len(mails) != 1 || mails[0].from != "alerts@example.com" || strings.Join(mails[0].to, ",") != "oncall@example.com,lead@example.com" {
+		t.Fatalf("unexpected mails: %+v", mails)
+	}
+
+	subject, content := parts(t, mails[0].data)
+	if subject != "[prod] [ERROR] Certificate expires in 3 days" {
+		t.Errorf("unexpected subject: %q", subject)
+	}
+	if text := content["text/plain"]; !strings.Contains(text, "Renew <it>") || !strings.Contains(text, "Certificate: api-tls") {
+		t.Errorf("unexpected text part: %q", text)
+	}
+	if html := content["text/html"]; !strings.Contains(html, "Renew &lt;it&gt;") || !strings.Contains(html, "<b>prod</b>") {
+		t.Errorf("unexpected html part: %q", html)
+	}
+}
+
+func Test_Email_Batching(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	email := notification.NewEmail(notification.EmailConfig{
+		Addr:          server.Addr(),
+		From:          "alerts@example.com",
+		To:            []string{"info@example.com"},
+		Environment:   "prod",
+		BatchInterval: time.Hour,
+		BatchSize:     2,
+	})
+
+	for _, title := range []string{"first", "second", "third"} {
+		if err := email.Send(context.Background(), notification.Message{Severity: notification.SeverityInfo, Title: title}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if got := len(server.Mails()); got != 1 {
+		t.Fatalf("expected a full batch to be sent, got %d mails", got)
+	}
+
+	if err := email.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mails := server.Mails()
+	if len(mails) != 2 {
+		t.Fatalf("expected the pending batch to be sent on close, got %d mails", len(mails))
+	}
+	subject, _ := parts(t, mails[0].data)
+	if subject != "[prod] [INFO] 2 notifications" {
+		t.Errorf("unexpected subject: %q", subject)
+	}
+}
+
+func Test_Email_HungServer(t *testing.T) {
+	// the server accepts connections but never responds
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	failed := make(chan notification.Message, 2)
+	email := notification.NewEmail(notification.EmailConfig{
+		Addr:          l.Addr().String(),
+		From:          "alerts@example.com",
+		To:            []string{"info@example.com"},
+		BatchInterval: time.Millisecond,
+		Timeout:       50 * time.Millisecond,
+	}, notification.WithEmailErrorHandler(func(msg notification.Message, err error) {
+		failed <- msg
+	}))
+
+	_ = email.Send(context.Background(), notification.Message{Title: "first"})
+	_ = email.Send(context.Background(), notification.Message{Title: "second"})
+	for i := 0; i < 2; i++ {
+		select {
+		case <-failed:
+		case <-time.After(time.Second):
+			t.Fatalf("expected the background flush to time out and report the messages")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := email.Close(ctx); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Labels set by an Enricher
+const (
+	LabelService   = "service"
+	LabelVersion   = "version"
+	LabelCommit    = "commit"
+	LabelHost      = "host"
+	LabelInstance  = "instance"
+	LabelRequestID = "request"
+	LabelTraceID   = "trace"
+)
+
+// EnricherConfig configures an Enricher, the values that are empty are not added to the messages
+type EnricherConfig struct {
+	Service string
+	Version string
+	Commit  string
+	// Hostname is os.Hostname if it is empty
+	Hostname string
+	// InstanceID identifies the running process, e.g. a pod or a VM instance. It is a random ID if it is empty.
+	InstanceID string
+	// Environment is used in the links of messages without LabelEnvironment
+	Environment string
+	// Links are added to every message, a link whose URL renders empty is left out
+	Links []LinkTemplate
+}
+
+// LinkTemplate is a link whose URL is a text/template rendered from an Enrichment, e.g.
+// "https://logs.example.com/?q={{query .RequestID}}". The "query" and "path" functions escape a value for a
+// query or a path. Use {{if .TraceID}} to only add a link when there is a trace.
+type LinkTemplate struct {
+	Text string
+	URL  string
+}
+
+// Enrichment is what the URLs of link templates are rendered from
+type Enrichment struct {
+	Service    string
+	Version    string
+	Commit     string
+	Hostname   string
+	InstanceID string
+	RequestID  string
+	TraceID    string
+	// Environment is the LabelEnvironment of the message, or the environment of the EnricherConfig
+	Environment string
+	// Timestamp is the time of the message, or when it was sent if it has none
+	Timestamp time.Time
+	Message   Message
+}
+
+type EnricherOption func(*Enricher)
+
+// WithEnricherIDs sets how the request and trace IDs are read from the context of Send, e.g. from the span of a
+// tracing library. The default reads the IDs set with ContextWithRequestID and ContextWithTraceID.
+func WithEnricherIDs(f func(ctx context.Context) (requestID, traceID string)) EnricherOption {
+	return func(e *Enricher) {
+		e.ids = f
+	}
+}
+
+// Enricher is a Sender that adds where a message comes from to it and sends it to next: the service, version,
+// commit, host, instance and the request and trace IDs of the context, as labels and as fields. Labels and fields
+// the message already has are kept. The Source of a message without one is the service.
+type Enricher struct {
+	next  Sender
+	cfg   EnricherConfig
+	links []linkTemplate
+	ids   func(ctx context.Context) (requestID, traceID string)
+}
+
+type linkTemplate struct {
+	text string
+	url  *template.Template
+}
+
+func parseLinkTemplate(l LinkTemplate) (linkTemplate, error) {
+	funcs := template.FuncMap{"query": url.QueryEscape, "path": url.PathEscape}
+	tmpl, err := template.New(l.Text).Funcs(funcs).Option("missingkey=error").Parse(l.URL)
+	if err != nil {
+		return linkTemplate{}, err
+	}
+	return linkTemplate{text: l.Text, url: tmpl}, nil
+}
+
+// NewEnricher returns an Enricher sending to next, an error is returned if a link template can't be parsed
+func NewEnricher(next Sender, cfg EnricherConfig, opts ...EnricherOption) (*Enricher, error) {
+	if cfg.Hostname == "" {
+		cfg.Hostname, _ = os.Hostname()
+	}
+	if cfg.InstanceID == "" {
+		cfg.InstanceID = uuid.New().String()
+	}
+
+	e := &Enricher{
+		next: next,
+		cfg:  cfg,
+		ids: func(ctx context.Context) (string, string) {
+			return RequestIDFromContext(ctx), TraceIDFromContext(ctx)
+		},
+	}
+	for _, l := range cfg.Links {
+		link, err := parseLinkTemplate(l)
+		if err != nil {
+			return nil, err
+		}
+		e.links = append(e.links, link)
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+// Send adds the enrichment to the message and sends it to next
+func (e *Enricher) Send(ctx context.Context, msg Message) error {
+	return e.next.Send(ctx, e.enrich(ctx, msg))
+}
+
+func (e *Enricher) enrich(ctx context.Context, msg Message) Message {
+	data := e.enrichment(ctx, msg)
+
+	// the labels, fields and links are copied, the caller may send the same message elsewhere
+	labels := make(map[string]string, len(msg.Labels)+7)
+	for k, v := range msg.Labels {
+		labels[k] = v
+	}
+	fields := append([]Field(nil), msg.Fields...)
+	hasField := map[string]bool{}
+	for _, f := range fields {
+		hasField[f.Key] = true
+	}
+
+	for _, x := range []struct{ label, field, value string }{
+		{LabelService, "Service", data.Service},
+		{LabelVersion, "Version", data.Version},
+		{LabelCommit, "Commit", data.Commit},
+		{LabelHost, "Host", data.Hostname},
+		{LabelInstance, "Instance", data.InstanceID},
+		{LabelRequestID, "Request ID", data.RequestID},
+		{LabelTraceID, "Trace ID", data.TraceID},
+	} {
+		if x.value == "" {
+			continue
+		}
+		if _, ok := labels[x.label]; !ok {
+			labels[x.label] = x.value
+		}
+		if !hasField[x.field] {
+			fields = append(fields, Field{Key: x.field, Value: x.value})
+		}
+	}
+	msg.Labels, msg.Fields = labels, fields
+
+	links := append([]Link(nil), msg.Links...)
+	for _, l := range e.links {
+		var b bytes.Buffer
+		// a link that can't be rendered is left out rather than failing the notification
+		if err := l.url.Execute(&b, data); err != nil || b.Len() == 0 {
+			continue
+		}
+		links = append(links, Link{Text: l.text, URL: b.String()})
+	}
+	msg.Links = links
+
+	if msg.Source == "" {
+		msg.Source = data.Service
+	}
+	return msg
+}
+
+func (e *Enricher) enrichment(ctx context.Context, msg Message) Enrichment {
+	requestID, traceID := e.ids(ctx)
+	data := Enrichment{
+		Service:     e.cfg.Service,
+		Version:     e.cfg.Version,
+		Commit:      e.cfg.Commit,
+		Hostname:    e.cfg.Hostname,
+		InstanceID:  e.cfg.InstanceID,
+		RequestID:   requestID,
+		TraceID:     traceID,
+		Environment: msg.Labels[LabelEnvironment],
+		Timestamp:   msg.Timestamp,
+		Message:     msg,
+	}
+	// the IDs of a message about a request that was sent later, e.g. by an outbox, are kept in its labels
+	if data.RequestID == "" {
+		data.RequestID = msg.Labels[LabelRequestID]
+	}
+	if data.TraceID == "" {
+		data.TraceID = msg.Labels[LabelTraceID]
+	}
+	if data.Environment == "" {
+		data.Environment = e.cfg.Environment
+	}
+	if data.Timestamp.IsZero() {
+		data.Timestamp = time.Now()
+	}
+	return data
+}
@@ -0,0 +1,102 @@
+package notification_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+	"github.com/SecuritasCrimePrediction/apitools-go/notification/notificationtest"
+)
+
+func Test_Enricher(t *testing.T) {
+	recorder := notificationtest.NewRecorder()
+	enricher, err := notification.NewEnricher(recorder, notification.EnricherConfig{
+		Service:     "orders",
+		Version:     "v1.4.2",
+		Commit:      "3f2c1ab",
+		Hostname:    "orders-7d9f-x2k4",
+		InstanceID:  "i-1",
+		Environment: "prod",
+		Links: []notification.LinkTemplate{
+			{Text: "Open logs", URL: "https://logs.example.com/?env={{.Environment}}&q={{query .RequestID}}"},
+			{Text: "Open trace", URL: "{{if .TraceID}}https://trace.example.com/{{path .TraceID}}{{end}}"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := notification.ContextWithRequestID(context.Background(), "req 1")
+	original := notification.Message{
+		Title:  "db down",
+		Fields: []notification.Field{{Key: "Host", Value: "db-1"}},
+		Labels: map[string]string{notification.LabelService: "billing"},
+	}
+	if err := enricher.Send(ctx, original); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg := recorder.Messages()[0]
+	for label, want := range map[string]string{
+		notification.LabelService:   "billing",
+		notification.LabelVersion:   "v1.4.2",
+		notification.LabelCommit:    "3f2c1ab",
+		notification.LabelHost:      "orders-7d9f-x2k4",
+		notification.LabelInstance:  "i-1",
+		notification.LabelRequestID: "req 1",
+	} {
+		if msg.Labels[label] != want {
+			t.Errorf("expected the label %s to be %q, got %q", label, want, msg.Labels[label])
+		}
+	}
+	if _, ok := msg.Labels[notification.LabelTraceID]; ok {
+		t.Errorf("expected no trace label without a trace ID")
+	}
+	fields := map[string]string{}
+	for _, f := range msg.Fields {
+		fields[f.Key] = f.Value
+	}
+	if fields["Host"] != "db-1" || fields["Service"] != "orders" || fields["Request ID"] != "req 1" {
+		t.Errorf("unexpected fields: %v", msg.Fields)
+	}
+	if len(msg.Links) != 1 || msg.Links[0].URL != "https://logs.example.com/?env=prod&q=req+1" {
+		t.Errorf("expected only the logs link, got: %v", msg.Links)
+	}
+	if msg.Source != "orders" {
+		t.Errorf("expected the service as source, got %q", msg.Source)
+	}
+	if len(original.Fields) != 1 || len(original.Labels) != 1 {
+		t.Errorf("expected the original message to be unchanged, got: %+v", original)
+	}
+
+	ctx = notification.ContextWithTraceID(context.Background(), "4bf92f35")
+	_ = enricher.Send(ctx, notification.Message{Title: "slow", Source: "worker"})
+	msg = recorder.Messages()[1]
+	if len(msg.Links) != 2 || msg.Links[1].URL != "https://trace.example.com/4bf92f35" || msg.Source != "worker" {
+		t.Errorf("expected the trace link and the source of the message, got: %+v", msg)
+	}
+}
+
+func Test_Enricher_IDs(t *testing.T) {
+	recorder := notificationtest.NewRecorder()
+	enricher, err := notification.NewEnricher(recorder, notification.EnricherConfig{Service: "orders"},
+		notification.WithEnricherIDs(func(ctx context.Context) (string, string) {
+			return "", "from-span"
+		}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = enricher.Send(context.Background(), notification.Message{Title: "slow"})
+
+	msg := recorder.Messages()[0]
+	if msg.Labels[notification.LabelTraceID] != "from-span" || msg.Labels[notification.LabelHost] == "" ||
+		msg.Labels[notification.LabelInstance] == "" {
+		t.Errorf("unexpected labels: %v", msg.Labels)
+	}
+
+	if _, err := notification.NewEnricher(recorder, notification.EnricherConfig{
+		Links: []notification.LinkTemplate{{Text: "Open logs", URL: "{{.RequestID"}},
+	}); err == nil {
+		t.Errorf("expected an error for an invalid template")
+	}
+}
@@ -0,0 +1,72 @@
+package notification
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Keys that can be used in FingerprintBy, any other key is read from the labels of the message
+const (
+	KeySeverity = "severity"
+	KeyTitle    = "title"
+	KeySource   = "source"
+	// KeyError is the error text normalized with NormalizeError
+	KeyError = "error"
+)
+
+// FingerprintFunc identifies messages about the same problem
+type FingerprintFunc func(msg Message) string
+
+// DefaultFingerprint identifies messages by severity, source, title, gRPC method, gRPC code and normalized error text
+var DefaultFingerprint = FingerprintBy(KeySeverity, KeySource, KeyTitle, LabelMethod, LabelCode, KeyError)
+
+// FingerprintBy returns a FingerprintFunc that hashes the given keys of a message.
+// The Fingerprint of a message is used as it is when it is set.
+func FingerprintBy(keys ...string) FingerprintFunc {
+	return func(msg Message) string {
+		if msg.Fingerprint != "" {
+			return msg.Fingerprint
+		}
+
+		h := sha1.New()
+		for _, key := range keys {
+			_, _ = fmt.Fprintf(h, "%s=%s\n", key, fingerprintValue(msg, key))
+		}
+		return hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+func fingerprintValue(msg Message, key string) string {
+	switch key {
+	case KeySeverity:
+		return msg.Severity.String()
+	case KeyTitle:
+		return msg.Title
+	case KeySource:
+		return msg.Source
+	case KeyError:
+		if msg.Err == nil {
+			return ""
+		}
+		return NormalizeError(msg.Err.Error())
+	}
+	return msg.Labels[key]
+}
+
+var (
+	uuidPattern   = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	hexPattern    = regexp.MustCompile(`(?i)\b(0x)?[0-9a-f]*[0-9][0-9a-f]*\b`)
+	spacesPattern = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeError replaces the parts of an error text that vary between occurrences of the same error,
+// such as ids, numbers and addresses, so that the texts can be compared
+func NormalizeError(text string) string {
+	text = uuidPattern.ReplaceAllString(text, "<uuid>")
+	text = hexPattern.ReplaceAllString(text, "<n>")
+	text = spacesPattern.ReplaceAllString(text, " ")
+	return strings.TrimSpace(text)
+}
@@ -3,30 +3,101 @@ package grpchook
 import (
 	"context"
 	"fmt"
+	"log"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/SecuritasCrimePrediction/apitools-go/notification"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+// SendErrorHandler is called when a notification could not be delivered to a recipient
+type SendErrorHandler func(ctx context.Context, msg notification.Message, err error)
+
+// InterceptorOption configures the notification interceptors.
+// An EndpointConfig is an InterceptorOption that adds the configuration of an endpoint.
+type InterceptorOption interface {
+	apply(i *interceptor)
+}
+
+type interceptorOptionFunc func(i *interceptor)
+
+func (f interceptorOptionFunc) apply(i *interceptor) {
+	f(i)
+}
+
+// WithSendErrorHandler sets the function that is called when a notification could not be delivered.
+// The default handler logs the error with the standard logger.
+func WithSendErrorHandler(f SendErrorHandler) InterceptorOption {
+	return interceptorOptionFunc(func(i *interceptor) {
+		i.handleSendErr = f
+	})
+}
+
+func defaultSendErrorHandler(_ context.Context, msg notification.Message, err error) {
+	log.Printf("grpchook: could not send notification %q: %v", msg.Title, err)
+}
+
 type interceptor struct {
-	configs    EndpointConfig
-	recipients []notification.Sender
+	configs       EndpointConfig
+	recipients    []notification.Sender
+	handleSendErr SendErrorHandler
+	failing       *failingEndpoints
 }
 
-// The hook for streaming endpoints
-// Assembles all endpoint configurations to one EndpointConfig and returns the hook function
-func StreamNotificationInterceptor(recipients []notification.Sender, configs ...EndpointConfig) grpc.StreamServerInterceptor {
-	endpointConfigs := EndpointConfig{}
-	for _, conf := range configs {
-		for k, v := range conf {
-			endpointConfigs[k] = v
-		}
+// failingEndpoints keeps track of the endpoints with notified errors, for the endpoints notifying on recovery
+type failingEndpoints struct {
+	mu        sync.Mutex
+	endpoints map[string]bool
+}
+
+// set marks the endpoint as failing or not, and returns whether it was failing before
+func (f *failingEndpoints) set(fullMethod string, failing bool) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	was := f.endpoints[fullMethod]
+	if failing {
+		f.endpoints[fullMethod] = true
+	} else {
+		delete(f.endpoints, fullMethod)
 	}
+	return was
+}
+
+func endpointOptions(configs []EndpointConfig) []InterceptorOption {
+	opts := make([]InterceptorOption, 0, len(configs))
+	for _, c := range configs {
+		opts = append(opts, c)
+	}
+	return opts
+}
+
+func newInterceptor(recipients []notification.Sender, opts []InterceptorOption) interceptor {
 	i := interceptor{
-		recipients: recipients,
-		configs:    endpointConfigs,
+		recipients:    recipients,
+		configs:       EndpointConfig{},
+		handleSendErr: defaultSendErrorHandler,
+		failing:       &failingEndpoints{endpoints: map[string]bool{}},
 	}
+	for _, opt := range opts {
+		opt.apply(&i)
+	}
+	return i
+}
+
+// The hook for streaming endpoints
+// Assembles all endpoint configurations to one EndpointConfig and returns the hook function
+func StreamNotificationInterceptor(recipients []notification.Sender, configs ...EndpointConfig) grpc.StreamServerInterceptor {
+	return StreamNotificationInterceptorWithOptions(recipients, endpointOptions(configs)...)
+}
+
+// StreamNotificationInterceptorWithOptions is StreamNotificationInterceptor with options besides the endpoint
+// configurations, such as WithSendErrorHandler
+func StreamNotificationInterceptorWithOptions(recipients []notification.Sender, opts ...InterceptorOption) grpc.StreamServerInterceptor {
+	i := newInterceptor(recipients, opts)
 	return i.streamHook
 }
 
@@ -45,31 +116,20 @@ func (i interceptor) streamHook(srv interface{}, stream grpc.ServerStream, info
 		return
 	}
 
-	// Determine whether a notification should be sent
-	if conf.ShouldNotifyForErr(stream.Context(), err) {
-		switch err {
-		case nil:
-			i.sendInfoMsg(endpointName, "")
-		default:
-			i.sendErrorMsg(endpointName, err.Error())
-		}
-	}
+	i.notify(stream.Context(), conf, info.FullMethod, "", err)
 	return
 }
 
 // The hook for unary endpoints
 // Assembles all endpoint configurations to one EndpointConfig and returns the hook function
 func UnaryNotificationInterceptor(recipients []notification.Sender, configs ...EndpointConfig) grpc.UnaryServerInterceptor {
-	endpointConfigs := EndpointConfig{}
-	for _, conf := range configs {
-		for k, v := range conf {
-			endpointConfigs[k] = v
-		}
-	}
-	i := interceptor{
-		recipients: recipients,
-		configs:    endpointConfigs,
-	}
+	return UnaryNotificationInterceptorWithOptions(recipients, endpointOptions(configs)...)
+}
+
+// UnaryNotificationInterceptorWithOptions is UnaryNotificationInterceptor with options besides the endpoint
+// configurations, such as WithSendErrorHandler
+func UnaryNotificationInterceptorWithOptions(recipients []notification.Sender, opts ...InterceptorOption) grpc.UnaryServerInterceptor {
+	i := newInterceptor(recipients, opts)
 	return i.unaryHook
 }
 
@@ -88,28 +148,118 @@ func (i interceptor) unaryHook(ctx context.Context, req interface{}, info *grpc.
 		return
 	}
 
+	i.notify(ctx, conf, info.FullMethod, resp, err)
+	return
+}
+
+// notify sends the notifications the endpoint configuration asks for after a request
+func (i interceptor) notify(ctx context.Context, conf Config, fullMethod string, resp interface{}, err error) {
 	// Determine whether a notification should be sent
 	if conf.ShouldNotifyForErr(ctx, err) {
 		switch err {
 		case nil:
-			i.sendInfoMsg(endpointName, resp)
+			i.sendInfoMsg(ctx, fullMethod, resp)
 		default:
-			i.sendErrorMsg(endpointName, err.Error())
+			i.sendErrorMsg(ctx, conf, fullMethod, err)
 		}
 	}
-	return
+
+	if conf.NotifyOnRecovery && err == nil && i.failing.set(fullMethod, false) {
+		i.sendRecoveryMsg(ctx, fullMethod)
+	}
 }
 
 // Send an info message on all notification channels
-func (i interceptor) sendInfoMsg(methodName string, info interface{}) {
-	for _, recipient := range i.recipients {
-		recipient.Info(fmt.Sprintf("Request to endpoint %s recieved\nExtra info: %+v", methodName, info))
-	}
+func (i interceptor) sendInfoMsg(ctx context.Context, fullMethod string, info interface{}) {
+	service, method := splitFullMethod(fullMethod)
+	i.send(ctx, notification.Message{
+		Severity:  notification.SeverityInfo,
+		Title:     fmt.Sprintf("Request to endpoint %s received", method),
+		Body:      fmt.Sprintf("Extra info: %+v", info),
+		Labels:    map[string]string{notification.LabelMethod: method},
+		Source:    service,
+		Timestamp: time.Now(),
+	})
 }
 
 // Send an error message on all notification channels
-func (i interceptor) sendErrorMsg(methodName string, errStr string) {
+func (i interceptor) sendErrorMsg(ctx context.Context, conf Config, fullMethod string, err error) {
+	service, method := splitFullMethod(fullMethod)
+	msg := notification.Message{
+		Severity: notification.SeverityAlert,
+		Title:    fmt.Sprintf("Error occurred in a call to %s", method),
+		Err:      err,
+		Labels: map[string]string{
+			notification.LabelMethod: method,
+			notification.LabelCode:   status.Code(err).String(),
+		},
+		Source:    service,
+		Timestamp: time.Now(),
+	}
+
+	if conf.NotifyOnRecovery {
+		msg.Fingerprint = recoveryFingerprint(fullMethod)
+		i.failing.set(fullMethod, true)
+	}
+
+	i.send(ctx, msg)
+}
+
+// Send a message resolving the errors of an endpoint on all notification channels
+func (i interceptor) sendRecoveryMsg(ctx context.Context, fullMethod string) {
+	service, method := splitFullMethod(fullMethod)
+	i.send(ctx, notification.Message{
+		Severity:    notification.SeverityInfo,
+		Title:       fmt.Sprintf("Endpoint %s recovered", method),
+		Body:        "A request was handled successfully after errors",
+		Labels:      map[string]string{notification.LabelMethod: method},
+		Source:      service,
+		Timestamp:   time.Now(),
+		Fingerprint: recoveryFingerprint(fullMethod),
+		Resolved:    true,
+	})
+}
+
+// recoveryFingerprint is the fingerprint shared by the error and recovery messages of an endpoint
+func recoveryFingerprint(fullMethod string) string {
+	return "grpchook:" + fullMethod
+}
+
+// send delivers the message to all recipients.
+// The request context is detached so that a cancelled request doesn't stop the notification about it.
+func (i interceptor) send(ctx context.Context, msg notification.Message) {
+	ctx = notification.DetachContext(withMetadataIDs(ctx))
 	for _, recipient := range i.recipients {
-		recipient.Alert(fmt.Sprintf("Error occurred in a call to %s\nError: %s", methodName, errStr))
+		if err := recipient.Send(ctx, msg); err != nil {
+			i.handleSendErr(ctx, msg, err)
+		}
+	}
+}
+
+// withMetadataIDs adds the request ID of the x-request-id header and the trace ID of the W3C traceparent header
+// of the request to the context, for a notification.Enricher. IDs that are already in the context are kept.
+func withMetadataIDs(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	if values := md.Get("x-request-id"); len(values) > 0 && notification.RequestIDFromContext(ctx) == "" {
+		ctx = notification.ContextWithRequestID(ctx, values[0])
+	}
+	if values := md.Get("traceparent"); len(values) > 0 && notification.TraceIDFromContext(ctx) == "" {
+		// version-traceid-parentid-flags
+		if parts := strings.Split(values[0], "-"); len(parts) == 4 && len(parts[1]) == 32 {
+			ctx = notification.ContextWithTraceID(ctx, parts[1])
+		}
+	}
+	return ctx
+}
+
+// splitFullMethod splits "/pkg.Service/Method" into the service and method name
+func splitFullMethod(fullMethod string) (string, string) {
+	parts := strings.Split(strings.TrimPrefix(fullMethod, "/"), "/")
+	if len(parts) < 2 {
+		return "", parts[len(parts)-1]
 	}
+	return parts[0], parts[len(parts)-1]
 }
@@ -0,0 +1,131 @@
+package grpchook_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+	"github.com/SecuritasCrimePrediction/apitools-go/notification/grpchook"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func Test_UnaryNotificationInterceptor(t *testing.T) {
+	var sent []notification.Message
+	recorder := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		sent = append(sent, msg)
+		return nil
+	})
+	failing := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		return errors.New("unavailable")
+	})
+
+	var sendErrs []error
+	interceptor := grpchook.UnaryNotificationInterceptorWithOptions(
+		[]notification.Sender{recorder, failing},
+		grpchook.NewEndpointConfig("Get"),
+		grpchook.WithSendErrorHandler(func(ctx context.Context, msg notification.Message, err error) {
+			sendErrs = append(sendErrs, err)
+		}),
+	)
+
+	handlerErr := status.Error(codes.Internal, "boom")
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, handlerErr
+	}
+
+	for _, method := range []string{"/test.Service/Get", "/test.Service/Other"} {
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		if err != handlerErr {
+			t.Fatalf("expected the handler error, got: %v", err)
+		}
+	}
+
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 notification, got: %d", len(sent))
+	}
+	msg := sent[0]
+	if msg.Severity != notification.SeverityAlert || msg.Err != handlerErr || msg.Source != "test.Service" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+	if msg.Labels[notification.LabelMethod] != "Get" || msg.Labels[notification.LabelCode] != codes.Internal.String() {
+		t.Errorf("unexpected labels: %v", msg.Labels)
+	}
+	if len(sendErrs) != 1 {
+		t.Errorf("expected 1 send error, got: %d", len(sendErrs))
+	}
+}
+
+func Test_UnaryNotificationInterceptor_NotifyOnRecovery(t *testing.T) {
+	var sent []notification.Message
+	recorder := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		sent = append(sent, msg)
+		return nil
+	})
+	interceptor := grpchook.UnaryNotificationInterceptor(
+		[]notification.Sender{recorder},
+		grpchook.NewEndpointConfig("Get", grpchook.DoNotifyOnRecovery()),
+	)
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"}
+	for _, err := range []error{nil, status.Error(codes.Unavailable, "a"), status.Error(codes.Internal, "b"), nil, nil} {
+		_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, err
+		})
+	}
+
+	if len(sent) != 3 {
+		t.Fatalf("expected 2 errors and 1 recovery, got: %d messages", len(sent))
+	}
+	recovery := sent[2]
+	if !recovery.Resolved || recovery.Severity != notification.SeverityInfo {
+		t.Errorf("expected a resolving info message, got: %+v", recovery)
+	}
+	if sent[0].Fingerprint == "" || sent[0].Fingerprint != sent[1].Fingerprint || sent[1].Fingerprint != recovery.Fingerprint {
+		t.Errorf("expected the messages to share a fingerprint")
+	}
+}
+
+func Test_UnaryNotificationInterceptor_MetadataIDs(t *testing.T) {
+	var requestID, traceID string
+	recorder := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		requestID, traceID = notification.RequestIDFromContext(ctx), notification.TraceIDFromContext(ctx)
+		return nil
+	})
+	interceptor := grpchook.UnaryNotificationInterceptor([]notification.Sender{recorder}, grpchook.NewEndpointConfig("Get"))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-request-id", "req-1",
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	))
+	_, _ = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Internal, "boom")
+	})
+
+	if requestID != "req-1" || traceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the IDs of the metadata, got %q and %q", requestID, traceID)
+	}
+}
+
+func Test_UnaryNotificationInterceptor_EndpointConfigs(t *testing.T) {
+	var sent []notification.Message
+	recorder := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		sent = append(sent, msg)
+		return nil
+	})
+	// the endpoint configurations can still be passed as a slice
+	configs := []grpchook.EndpointConfig{grpchook.NewEndpointConfig("Get"), grpchook.NewEndpointConfig("List")}
+	interceptor := grpchook.UnaryNotificationInterceptor([]notification.Sender{recorder}, configs...)
+
+	for _, method := range []string{"/test.Service/Get", "/test.Service/List", "/test.Service/Other"} {
+		_, _ = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.Internal, "boom")
+		})
+	}
+	if len(sent) != 2 {
+		t.Errorf("expected notifications for the configured endpoints, got: %d", len(sent))
+	}
+}
@@ -17,6 +17,7 @@ type Config struct {
 	CustomShouldNotify ShouldNotifyForErrFunc
 	SkipErrors         bool
 	NotifyOnSuccess    bool
+	NotifyOnRecovery   bool
 	ErrorCodes         []codes.Code
 }
 
@@ -52,6 +53,15 @@ func DoNotifyOnSuccess() EndpointOption {
 	}
 }
 
+// Set if a resolving notification should be sent when the endpoint handles a request successfully after
+// an error was notified. The error notifications of the endpoint then share one fingerprint, so that e.g.
+// PagerDuty resolves the incident they triggered.
+func DoNotifyOnRecovery() EndpointOption {
+	return func(c *Config) {
+		c.NotifyOnRecovery = true
+	}
+}
+
 // Set to skip message notifications when a gRPC error occurs.
 func DoSkipErrors() EndpointOption {
 	return func(c *Config) {
@@ -98,3 +108,10 @@ func (c Config) ShouldNotifyForErr(ctx context.Context, err error) bool {
 	}
 	return false
 }
+
+// apply adds the endpoint configurations to the interceptor, which makes EndpointConfig an InterceptorOption
+func (e EndpointConfig) apply(i *interceptor) {
+	for k, v := range e {
+		i.configs[k] = v
+	}
+}
@@ -0,0 +1,128 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultHTTPTimeout is the timeout of the HTTP client used by the webhook senders unless another client is set
+const DefaultHTTPTimeout = 10 * time.Second
+
+var defaultHTTPClient = &http.Client{Timeout: DefaultHTTPTimeout}
+
+// StatusError is returned when a notification endpoint responds with a non 2xx status code
+type StatusError struct {
+	StatusCode int
+	// RetryAfter is the delay requested by the Retry-After header, zero if the header was not set
+	RetryAfter time.Duration
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("notification endpoint responded with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("notification endpoint responded with status %d: %s", e.StatusCode, e.Body)
+}
+
+// Temporary reports whether the request may succeed if it is retried, which is the case when
+// the endpoint is rate limiting (429) or has a server error (5xx)
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// IsTemporary reports whether sending may succeed if it is retried after the error.
+// It is true for temporary StatusErrors and network timeouts.
+func IsTemporary(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	return false
+}
+
+// postJSON posts the payload as JSON and returns a *StatusError if the response isn't successful
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}, header http.Header) error {
+	return callJSON(ctx, client, url, payload, header, nil)
+}
+
+// callJSON is postJSON that also decodes the JSON response into out
+func callJSON(ctx context.Context, client *http.Client, url string, payload interface{}, header http.Header, out interface{}) error {
+	marshal, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := post(ctx, client, url, "application/json", marshal, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// post sends the request and returns the response if it is successful, the caller must close the body.
+// A *StatusError is returned if the response isn't successful.
+func post(ctx context.Context, client *http.Client, url string, contentType string, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			Body:       string(respBody),
+		}
+	}
+
+	return resp, nil
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -1,6 +1,39 @@
 package notification
 
+import "context"
+
+// Sender delivers a Message on a notification channel
 type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SenderFunc is a function that can be used as a Sender
+type SenderFunc func(ctx context.Context, msg Message) error
+
+func (f SenderFunc) Send(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+// LegacySender is the two method style that notification channels used to implement
+type LegacySender interface {
 	Alert(msg string)
 	Info(msg string)
 }
+
+// FromLegacy adapts a LegacySender to a Sender.
+// Alerts are sent with Alert and everything else with Info, the message is rendered with Message.Text.
+func FromLegacy(s LegacySender) Sender {
+	return SenderFunc(func(_ context.Context, msg Message) error {
+		text := msg.Title
+		if body := msg.Text(); body != "" {
+			text += "\n" + body
+		}
+
+		if msg.Severity >= SeverityAlert {
+			s.Alert(text)
+		} else {
+			s.Info(text)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,55 @@
+package notification
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogSender is a Sender that writes messages to a zap logger, e.g. as the fallback of a Breaker.
+// Errors and critical messages are logged at error level, warnings at warn level, debug messages at debug level
+// and the rest at info level.
+// The logger must not have a Core sending to the same channel, that would loop.
+type LogSender struct {
+	log *zap.Logger
+}
+
+// NewLogSender returns a LogSender writing to log
+func NewLogSender(log *zap.Logger) *LogSender {
+	return &LogSender{log: log}
+}
+
+// Send logs the message with its fields, labels, error and source as zap fields
+func (s *LogSender) Send(_ context.Context, msg Message) error {
+	fields := make([]zap.Field, 0, len(msg.Fields)+len(msg.Labels)+3)
+	for _, f := range msg.Fields {
+		fields = append(fields, zap.String(f.Key, f.Value))
+	}
+	for k, v := range msg.Labels {
+		fields = append(fields, zap.String(k, v))
+	}
+	if msg.Body != "" {
+		fields = append(fields, zap.String("body", msg.Body))
+	}
+	if msg.Source != "" {
+		fields = append(fields, zap.String("source", msg.Source))
+	}
+	if msg.Err != nil {
+		fields = append(fields, zap.Error(msg.Err))
+	}
+
+	level := zapcore.InfoLevel
+	switch {
+	case msg.Severity >= SeverityError:
+		level = zapcore.ErrorLevel
+	case msg.Severity == SeverityWarning:
+		level = zapcore.WarnLevel
+	case msg.Severity <= SeverityDebug:
+		level = zapcore.DebugLevel
+	}
+	if ce := s.log.Check(level, msg.Title); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Labels set by the packages in this module
+const (
+	// LabelMethod is the gRPC method a message is about
+	LabelMethod = "method"
+	// LabelCode is the gRPC status code of an error
+	LabelCode = "code"
+)
+
+// Field is a key/value pair with extra information about a Message
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Link is a link to more information about a Message, e.g. logs or a trace
+type Link struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// Message is a notification that can be sent with a Sender
+type Message struct {
+	Severity Severity
+	Title    string
+	Body     string
+	// Fields are shown together with the body, in order
+	Fields []Field
+	// Err is the error the message is about, if any
+	Err error
+	// Links are shown as buttons where the channel supports it
+	Links []Link
+	// Labels are used to identify and route messages, they are not necessarily shown
+	Labels map[string]string
+	// Fingerprint identifies messages about the same problem, if it is empty the fingerprint is computed
+	// from the message, see DefaultFingerprint
+	Fingerprint string
+	// Resolved marks the message as the clearing of the problem with the same fingerprint
+	Resolved bool
+	// Source is the name of the service that sent the message
+	Source    string
+	Timestamp time.Time
+}
+
+// Text renders the body, fields, error, source and links of the message as plain text
+func (m Message) Text() string {
+	var lines []string
+	if m.Body != "" {
+		lines = append(lines, m.Body)
+	}
+	for _, f := range m.Fields {
+		lines = append(lines, fmt.Sprintf("%s: %s", f.Key, f.Value))
+	}
+	if m.Err != nil {
+		lines = append(lines, fmt.Sprintf("Error: %v", m.Err))
+	}
+	if m.Source != "" {
+		lines = append(lines, fmt.Sprintf("Source: %s", m.Source))
+	}
+	for _, l := range m.Links {
+		lines = append(lines, fmt.Sprintf("%s: %s", l.Text, l.URL))
+	}
+	return strings.Join(lines, "\n")
+}
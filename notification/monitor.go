@@ -0,0 +1,253 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// LabelHeartbeat is the name of the heartbeat a missed or recovered message is about
+const LabelHeartbeat = "heartbeat"
+
+type MonitorOption func(*Monitor)
+
+// WithMonitorSource sets the Source of the messages, e.g. the name of the service
+func WithMonitorSource(source string) MonitorOption {
+	return func(m *Monitor) {
+		m.source = source
+	}
+}
+
+// WithMonitorResolution sets how often the heartbeats are checked, the default is one second
+func WithMonitorResolution(d time.Duration) MonitorOption {
+	return func(m *Monitor) {
+		m.resolution = d
+	}
+}
+
+// WithMonitorClock replaces the system clock, used in tests
+func WithMonitorClock(c Clock) MonitorOption {
+	return func(m *Monitor) {
+		m.clock = c
+	}
+}
+
+// WithMonitorErrorHandler sets the function that is called when a message could not be sent.
+// The default handler logs the error with the standard logger.
+func WithMonitorErrorHandler(f DeliveryErrorHandler) MonitorOption {
+	return func(m *Monitor) {
+		m.handleErr = f
+	}
+}
+
+// Monitor is a dead man's switch for code that fails by not running, such as batch jobs. The code calls Beat
+// when it succeeds, and when no beat arrives within the interval of the heartbeat an alert is sent.
+// When the beats resume a Resolved message is sent.
+type Monitor struct {
+	senders    []Sender
+	source     string
+	resolution time.Duration
+	clock      Clock
+	handleErr  DeliveryErrorHandler
+
+	mu         sync.Mutex
+	heartbeats map[string]*heartbeat
+	// queue holds the messages in the order they happened, a single worker sends them so that the recovery of
+	// a heartbeat is never delivered before its alert
+	queue     []Message
+	wake      chan struct{}
+	closed    chan struct{}
+	done      chan struct{}
+	delivered chan struct{}
+}
+
+type heartbeat struct {
+	interval time.Duration
+	last     time.Time
+	missing  bool
+}
+
+// NewMonitor starts a Monitor sending to the senders
+func NewMonitor(senders []Sender, opts ...MonitorOption) *Monitor {
+	m := &Monitor{
+		senders:    senders,
+		resolution: time.Second,
+		clock:      SystemClock(),
+		handleErr: func(msg Message, err error) {
+			log.Printf("notification: could not send %q: %v", msg.Title, err)
+		},
+		heartbeats: map[string]*heartbeat{},
+		wake:       make(chan struct{}, 1),
+		closed:     make(chan struct{}),
+		done:       make(chan struct{}),
+		delivered:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	go m.run(m.clock.After(m.resolution))
+	go m.deliver()
+	return m
+}
+
+// Expect adds a heartbeat that must beat at least once per interval, the first interval starts now.
+// Expecting a heartbeat that already exists changes its interval.
+func (m *Monitor) Expect(name string, interval time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hb, ok := m.heartbeats[name]; ok {
+		hb.interval = interval
+		return
+	}
+	m.heartbeats[name] = &heartbeat{interval: interval, last: m.clock.Now()}
+}
+
+// Beat records a beat of the heartbeat, if it was missing the recovery is sent in the background.
+// Beats of heartbeats that are not expected are ignored.
+func (m *Monitor) Beat(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hb, ok := m.heartbeats[name]
+	if !ok {
+		return
+	}
+	now := m.clock.Now()
+	if hb.missing {
+		hb.missing = false
+		m.sendAsync(m.recovered(name, hb, now))
+	}
+	hb.last = now
+}
+
+// Close stops checking the heartbeats and waits for the messages that are being sent.
+// If ctx is done first the context error is returned.
+func (m *Monitor) Close(ctx context.Context) error {
+	m.mu.Lock()
+	select {
+	case <-m.closed:
+	default:
+		close(m.closed)
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		<-m.done
+		<-m.delivered
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Monitor) run(next <-chan time.Time) {
+	defer close(m.done)
+	for {
+		select {
+		case <-next:
+		case <-m.closed:
+			return
+		}
+
+		// the next check is scheduled before this one, so that a clock that is advanced after a message
+		// was sent always triggers it
+		next = m.clock.After(m.resolution)
+		m.check()
+	}
+}
+
+func (m *Monitor) check() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	for name, hb := range m.heartbeats {
+		if !hb.missing && now.Sub(hb.last) > hb.interval {
+			hb.missing = true
+			m.sendAsync(m.missed(name, hb, now))
+		}
+	}
+}
+
+// sendAsync queues the message for the worker, it is called with mu held
+func (m *Monitor) sendAsync(msg Message) {
+	select {
+	case <-m.closed:
+		return
+	default:
+	}
+
+	m.queue = append(m.queue, msg)
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliver sends the queued messages to all the senders in order, until the monitor is closed and the queue is empty
+func (m *Monitor) deliver() {
+	defer close(m.delivered)
+	for {
+		m.mu.Lock()
+		if len(m.queue) == 0 {
+			m.mu.Unlock()
+			select {
+			case <-m.wake:
+				continue
+			case <-m.closed:
+			}
+			// nothing is queued after the monitor is closed, send what was queued before
+			m.mu.Lock()
+			if len(m.queue) == 0 {
+				m.mu.Unlock()
+				return
+			}
+		}
+		msg := m.queue[0]
+		m.queue = m.queue[1:]
+		m.mu.Unlock()
+
+		for _, sender := range m.senders {
+			if err := sender.Send(context.Background(), msg); err != nil {
+				m.handleErr(msg, err)
+			}
+		}
+	}
+}
+
+func (m *Monitor) missed(name string, hb *heartbeat, now time.Time) Message {
+	return Message{
+		Severity: SeverityAlert,
+		Title:    "Heartbeat missed: " + name,
+		Body:     fmt.Sprintf("No beat for %s, expected every %s", formatDuration(now.Sub(hb.last).Round(time.Second)), formatDuration(hb.interval)),
+		Fields: []Field{
+			{Key: "Last beat", Value: hb.last.Format(time.RFC3339)},
+		},
+		Labels:      map[string]string{LabelHeartbeat: name},
+		Fingerprint: "heartbeat/" + name,
+		Source:      m.source,
+		Timestamp:   now,
+	}
+}
+
+func (m *Monitor) recovered(name string, hb *heartbeat, now time.Time) Message {
+	return Message{
+		Severity:    SeverityInfo,
+		Title:       "Heartbeat recovered: " + name,
+		Body:        fmt.Sprintf("Beating again after %s", formatDuration(now.Sub(hb.last).Round(time.Second))),
+		Labels:      map[string]string{LabelHeartbeat: name},
+		Fingerprint: "heartbeat/" + name,
+		Resolved:    true,
+		Source:      m.source,
+		Timestamp:   now,
+	}
+}
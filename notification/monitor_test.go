@@ -0,0 +1,95 @@
+package notification_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+	"github.com/SecuritasCrimePrediction/apitools-go/notification/notificationtest"
+)
+
+func Test_Monitor(t *testing.T) {
+	recorder := notificationtest.NewRecorder()
+	clock := newFakeClock()
+	monitor := notification.NewMonitor([]notification.Sender{recorder},
+		notification.WithMonitorClock(clock),
+		notification.WithMonitorSource("batch"))
+	defer monitor.Close(context.Background())
+
+	monitor.Expect("nightly-import", time.Hour)
+	monitor.Expect("cleanup", 24*time.Hour)
+	monitor.Beat("unknown")
+
+	clock.Advance(30 * time.Minute)
+	monitor.Beat("nightly-import")
+	clock.Advance(45 * time.Minute)
+	if _, ok := recorder.WaitForMessage(50*time.Millisecond, notificationtest.Any()); ok {
+		t.Fatalf("expected no message while the heartbeats beat in time, got: %v", recorder.Messages())
+	}
+
+	clock.Advance(30 * time.Minute)
+	missed, ok := recorder.WaitForMessage(time.Second, notificationtest.WithTitle("Heartbeat missed: nightly-import"))
+	if !ok {
+		t.Fatalf("expected an alert for the missed heartbeat, got: %v", recorder.Messages())
+	}
+	if missed.Severity != notification.SeverityAlert || missed.Source != "batch" || missed.Body != "No beat for 1h15m, expected every 1h" {
+		t.Errorf("unexpected alert: %+v", missed)
+	}
+
+	// the alert is sent once, not at every check
+	clock.Advance(time.Hour)
+	clock.Advance(time.Hour)
+	if _, ok := recorder.WaitForMessage(50*time.Millisecond, notificationtest.All(
+		notificationtest.WithTitle("Heartbeat missed: nightly-import"),
+		func(msg notification.Message) bool { return msg.Timestamp != missed.Timestamp },
+	)); ok {
+		t.Errorf("expected one alert, got: %v", recorder.Messages())
+	}
+
+	monitor.Beat("nightly-import")
+	recovered, ok := recorder.WaitForMessage(time.Second, notificationtest.WithTitle("Heartbeat recovered: nightly-import"))
+	if !ok || !recovered.Resolved || recovered.Fingerprint != missed.Fingerprint {
+		t.Errorf("expected a resolved message for the alert, got: %+v", recovered)
+	}
+	if recorder.Len() != 2 {
+		t.Errorf("expected only the alert and the recovery, got: %v", recorder.Messages())
+	}
+}
+
+func Test_Monitor_Order(t *testing.T) {
+	var mu sync.Mutex
+	var titles []string
+	missedSending := make(chan struct{})
+	release := make(chan struct{})
+	slow := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		if !msg.Resolved {
+			close(missedSending)
+			<-release
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		titles = append(titles, msg.Title)
+		return nil
+	})
+	clock := newFakeClock()
+	monitor := notification.NewMonitor([]notification.Sender{slow}, notification.WithMonitorClock(clock))
+
+	monitor.Expect("nightly-import", time.Hour)
+	clock.Advance(2 * time.Hour)
+	<-missedSending
+	// the beat arrives while the alert is still being sent, the recovery waits for it
+	monitor.Beat("nightly-import")
+	close(release)
+	if err := monitor.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(titles, ",") != "Heartbeat missed: nightly-import,Heartbeat recovered: nightly-import" {
+		t.Errorf("expected the alert before the recovery, got: %v", titles)
+	}
+}
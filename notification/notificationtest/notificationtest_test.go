@@ -0,0 +1,133 @@
+package notificationtest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+	"github.com/SecuritasCrimePrediction/apitools-go/notification/notificationtest"
+)
+
+func Test_Recorder(t *testing.T) {
+	recorder := notificationtest.NewRecorder()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = recorder.Send(context.Background(), notification.Message{Severity: notification.SeverityInfo, Title: "info"})
+		}()
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = recorder.Send(context.Background(), notification.Message{
+			Severity: notification.SeverityAlert,
+			Title:    "db down",
+			Labels:   map[string]string{notification.LabelMethod: "Get"},
+		})
+	}()
+
+	msg, ok := recorder.WaitForMessage(time.Second, notificationtest.All(
+		notificationtest.WithSeverity(notification.SeverityAlert),
+		notificationtest.WithLabel(notification.LabelMethod, "Get"),
+	))
+	if !ok || msg.Title != "db down" {
+		t.Fatalf("expected the alert, got: %+v", msg)
+	}
+
+	wg.Wait()
+	if infos, ok := recorder.WaitForMessages(time.Second, 10, notificationtest.WithTitle("info")); !ok || len(infos) != 10 {
+		t.Errorf("expected 10 info messages, got: %d", len(infos))
+	}
+	if recorder.Len() != 11 || len(recorder.Matching(notificationtest.Any())) != 11 {
+		t.Errorf("expected 11 messages, got: %d", recorder.Len())
+	}
+	if _, ok := recorder.WaitForMessage(10*time.Millisecond, notificationtest.WithTitle("never")); ok {
+		t.Errorf("expected no message to match")
+	}
+
+	recorder.Reset()
+	recorder.SetError(errors.New("unavailable"))
+	if err := recorder.Send(context.Background(), notification.Message{}); err == nil || recorder.Len() != 0 {
+		t.Errorf("expected the set error and nothing recorded, got: %v", err)
+	}
+}
+
+func Test_SlackServer(t *testing.T) {
+	server := notificationtest.NewSlackServer(t)
+	slack := notification.NewSlack(server.URL+"/info", server.URL+"/alert", "prod", false)
+
+	err := slack.Send(context.Background(), notification.Message{
+		Severity: notification.SeverityAlert,
+		Title:    "db down",
+		Fields:   []notification.Field{{Key: "Table", Value: "users"}},
+		Links:    []notification.Link{{Text: "Open logs", URL: "https://logs.example.com"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	alerts := server.PayloadsTo("/alert")
+	if len(alerts) != 1 {
+		t.Fatalf("expected one alert, got: %+v", server.Requests())
+	}
+	for _, want := range []string{"db down", "*Table:* users", "Open logs", "Environment: *prod*"} {
+		if !strings.Contains(alerts[0].AllText(), want) {
+			t.Errorf("expected the alert to contain %q, got: %s", want, alerts[0].AllText())
+		}
+	}
+
+	server.RateLimitNext(2*time.Second, 1)
+	server.FailNext(http.StatusInternalServerError, 1)
+
+	var statusErr *notification.StatusError
+	if err := slack.Info("a", "b"); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests || statusErr.RetryAfter != 2*time.Second {
+		t.Errorf("expected a rate limit error, got: %v", err)
+	}
+	if err := slack.Info("a", "b"); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected a server error, got: %v", err)
+	}
+	if err := slack.Info("a", "b"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(server.Requests()) != 4 || len(server.Payloads()) != 2 {
+		t.Errorf("expected 4 requests of which 2 succeeded, got: %+v", server.Requests())
+	}
+}
+
+func Test_SlackServer_Validation(t *testing.T) {
+	server := notificationtest.NewSlackServer(t)
+
+	tests := []struct {
+		name    string
+		payload string
+		wantErr string
+	}{
+		{"empty", `{}`, "no text and no blocks"},
+		{"unknown field", `{"text": "a", "color": "red"}`, "unknown field"},
+		{"unknown block", `{"blocks": [{"type": "table"}]}`, "unknown block type"},
+		{"empty section", `{"blocks": [{"type": "section"}]}`, "text or fields"},
+		{"long text", `{"blocks": [{"type": "section", "text": {"type": "mrkdwn", "text": "` + strings.Repeat("x", 3001) + `"}}]}`, "limit is 3000"},
+		{"markdown header", `{"blocks": [{"type": "header", "text": {"type": "mrkdwn", "text": "a"}}]}`, "plain_text"},
+		{"bad button", `{"blocks": [{"type": "actions", "elements": [{"type": "button", "text": "a"}]}]}`, "plain_text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(server.URL, "application/json", strings.NewReader(tt.payload))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+			requests := server.Requests()
+			last := requests[len(requests)-1]
+			if resp.StatusCode != http.StatusBadRequest || last.Err == nil || !strings.Contains(last.Err.Error(), tt.wantErr) {
+				t.Errorf("expected %q, got: %d %v", tt.wantErr, resp.StatusCode, last.Err)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Package notificationtest has helpers for testing code that sends notifications:
+// a Recorder Sender that records the messages it is sent, and a fake Slack webhook server.
+package notificationtest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+// MatchFunc reports whether a message is the one looked for
+type MatchFunc func(msg notification.Message) bool
+
+// Any matches every message
+func Any() MatchFunc {
+	return func(notification.Message) bool { return true }
+}
+
+// WithTitle matches messages whose title contains the text
+func WithTitle(text string) MatchFunc {
+	return func(msg notification.Message) bool { return strings.Contains(msg.Title, text) }
+}
+
+// WithSeverity matches messages with the severity
+func WithSeverity(s notification.Severity) MatchFunc {
+	return func(msg notification.Message) bool { return msg.Severity == s }
+}
+
+// WithLabel matches messages with the label set to the value
+func WithLabel(key, value string) MatchFunc {
+	return func(msg notification.Message) bool {
+		v, ok := msg.Labels[key]
+		return ok && v == value
+	}
+}
+
+// All matches messages that match all the functions
+func All(matches ...MatchFunc) MatchFunc {
+	return func(msg notification.Message) bool {
+		for _, match := range matches {
+			if !match(msg) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Recorder is a Sender that records the messages it is sent. It is safe for concurrent use,
+// so it can be used with senders that send in the background, such as a Dispatcher.
+type Recorder struct {
+	mu       sync.Mutex
+	messages []notification.Message
+	err      error
+	// changed is closed and replaced when a message is recorded
+	changed chan struct{}
+}
+
+// NewRecorder returns an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{changed: make(chan struct{})}
+}
+
+// Send records the message and returns the error set with SetError
+func (r *Recorder) Send(_ context.Context, msg notification.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.messages = append(r.messages, msg)
+	close(r.changed)
+	r.changed = make(chan struct{})
+	return nil
+}
+
+// SetError makes Send fail with err without recording the message, until it is set to nil
+func (r *Recorder) SetError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// Messages returns the recorded messages in the order they were sent
+func (r *Recorder) Messages() []notification.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]notification.Message(nil), r.messages...)
+}
+
+// Matching returns the recorded messages that match
+func (r *Recorder) Matching(match MatchFunc) []notification.Message {
+	var matching []notification.Message
+	for _, msg := range r.Messages() {
+		if match(msg) {
+			matching = append(matching, msg)
+		}
+	}
+	return matching
+}
+
+// Len returns the number of recorded messages
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.messages)
+}
+
+// Reset forgets the recorded messages
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = nil
+}
+
+// WaitForMessage returns the first recorded message that matches, waiting for it to be sent
+// if it hasn't been yet. It returns false if no message matched within the timeout.
+func (r *Recorder) WaitForMessage(timeout time.Duration, match MatchFunc) (notification.Message, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		r.mu.Lock()
+		for _, msg := range r.messages {
+			if match(msg) {
+				r.mu.Unlock()
+				return msg, true
+			}
+		}
+		changed := r.changed
+		r.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return notification.Message{}, false
+		}
+	}
+}
+
+// WaitForMessages waits until n messages match and returns them.
+// It returns the messages that matched so far and false if fewer matched within the timeout.
+func (r *Recorder) WaitForMessages(timeout time.Duration, n int, match MatchFunc) ([]notification.Message, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		r.mu.Lock()
+		changed := r.changed
+		r.mu.Unlock()
+
+		matching := r.Matching(match)
+		if len(matching) >= n {
+			return matching, true
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return matching, false
+		}
+	}
+}
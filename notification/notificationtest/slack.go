@@ -0,0 +1,328 @@
+package notificationtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+// SlackPayload is the body of a request to a Slack incoming webhook
+type SlackPayload struct {
+	Text   string       `json:"text,omitempty"`
+	Blocks []SlackBlock `json:"blocks,omitempty"`
+}
+
+// SlackBlock is a Block Kit block of a SlackPayload, elements are kept as they were received
+type SlackBlock struct {
+	Type      string            `json:"type"`
+	BlockID   string            `json:"block_id,omitempty"`
+	Text      *SlackText        `json:"text,omitempty"`
+	Fields    []SlackText       `json:"fields,omitempty"`
+	Elements  []json.RawMessage `json:"elements,omitempty"`
+	Accessory json.RawMessage   `json:"accessory,omitempty"`
+}
+
+type SlackText struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Emoji    *bool  `json:"emoji,omitempty"`
+	Verbatim *bool  `json:"verbatim,omitempty"`
+}
+
+// AllText returns the fallback text and the texts of all the blocks, one per line, to check what a message says
+func (p SlackPayload) AllText() string {
+	lines := []string{p.Text}
+	for _, b := range p.Blocks {
+		if b.Text != nil {
+			lines = append(lines, b.Text.Text)
+		}
+		for _, f := range b.Fields {
+			lines = append(lines, f.Text)
+		}
+		for _, e := range b.Elements {
+			var element struct {
+				Text json.RawMessage `json:"text"`
+			}
+			_ = json.Unmarshal(e, &element)
+			var text SlackText
+			if json.Unmarshal(element.Text, &text) == nil {
+				lines = append(lines, text.Text)
+			} else if s, err := strconv.Unquote(string(element.Text)); err == nil {
+				lines = append(lines, s)
+			}
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// SlackRequest is a request received by a SlackServer
+type SlackRequest struct {
+	Path string
+	Body []byte
+	// Payload is the decoded body, it is only set if the body is a valid payload
+	Payload SlackPayload
+	// Err is why the payload was rejected, if it was
+	Err error
+	// Status is the status code the server responded with
+	Status int
+}
+
+// SlackServer is a fake Slack incoming webhook. It accepts posts to any path, so a path per hook can be used,
+// e.g. server.URL+"/info" and server.URL+"/alert". Payloads are validated against the Block Kit schema and limits
+// and rejected with 400 invalid_blocks like Slack does. Failures can be simulated with FailNext and RateLimitNext.
+type SlackServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []SlackRequest
+	failures []slackFailure
+}
+
+type slackFailure struct {
+	status     int
+	retryAfter time.Duration
+}
+
+// NewSlackServer starts a SlackServer that is closed when the test ends
+func NewSlackServer(t testing.TB) *SlackServer {
+	s := &SlackServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// FailNext makes the server respond to the next n requests with the status code, e.g. 500
+func (s *SlackServer) FailNext(status, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, slackFailure{status: status})
+	}
+}
+
+// RateLimitNext makes the server respond to the next n requests with 429 and a Retry-After header
+func (s *SlackServer) RateLimitNext(retryAfter time.Duration, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, slackFailure{status: http.StatusTooManyRequests, retryAfter: retryAfter})
+	}
+}
+
+// Requests returns all the requests the server received, including the failed ones
+func (s *SlackServer) Requests() []SlackRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SlackRequest(nil), s.requests...)
+}
+
+// Payloads returns the payloads that were accepted, in the order they were received
+func (s *SlackServer) Payloads() []SlackPayload {
+	var payloads []SlackPayload
+	for _, r := range s.Requests() {
+		if r.Status == http.StatusOK {
+			payloads = append(payloads, r.Payload)
+		}
+	}
+	return payloads
+}
+
+// PayloadsTo returns the accepted payloads that were posted to the path
+func (s *SlackServer) PayloadsTo(path string) []SlackPayload {
+	var payloads []SlackPayload
+	for _, r := range s.Requests() {
+		if r.Status == http.StatusOK && r.Path == path {
+			payloads = append(payloads, r.Payload)
+		}
+	}
+	return payloads
+}
+
+func (s *SlackServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	req := SlackRequest{Path: r.URL.Path, Body: body}
+
+	s.mu.Lock()
+	var failure *slackFailure
+	if len(s.failures) > 0 {
+		failure = &s.failures[0]
+		s.failures = s.failures[1:]
+	}
+	s.mu.Unlock()
+
+	var response string
+	switch {
+	case r.Method != http.MethodPost:
+		req.Status, response = http.StatusMethodNotAllowed, "invalid_method"
+	case failure != nil:
+		req.Status, response = failure.status, http.StatusText(failure.status)
+		if failure.retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(failure.retryAfter.Seconds())))
+		}
+	default:
+		req.Payload, req.Err = decodeSlackPayload(r.Header.Get("Content-Type"), body)
+		if req.Err != nil {
+			req.Status, response = http.StatusBadRequest, "invalid_blocks"
+		} else {
+			req.Status, response = http.StatusOK, "ok"
+		}
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	w.WriteHeader(req.Status)
+	_, _ = w.Write([]byte(response))
+}
+
+func decodeSlackPayload(contentType string, body []byte) (SlackPayload, error) {
+	var payload SlackPayload
+	if !strings.HasPrefix(contentType, "application/json") {
+		return payload, fmt.Errorf("content type is %q, not application/json", contentType)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&payload); err != nil {
+		return payload, err
+	}
+	return payload, validateSlackPayload(payload)
+}
+
+// validateSlackPayload checks the parts of the Block Kit schema and limits that the senders of this module use
+func validateSlackPayload(p SlackPayload) error {
+	if p.Text == "" && len(p.Blocks) == 0 {
+		return fmt.Errorf("payload has no text and no blocks")
+	}
+	if len(p.Blocks) > notification.SlackMaxBlocks {
+		return fmt.Errorf("payload has %d blocks, the limit is %d", len(p.Blocks), notification.SlackMaxBlocks)
+	}
+
+	for i, b := range p.Blocks {
+		if err := validateSlackBlock(b); err != nil {
+			return fmt.Errorf("blocks[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateSlackBlock(b SlackBlock) error {
+	switch b.Type {
+	case "divider":
+		if b.Text != nil || len(b.Fields) > 0 || len(b.Elements) > 0 {
+			return fmt.Errorf("divider has content")
+		}
+	case "header":
+		if b.Text == nil || b.Text.Type != "plain_text" {
+			return fmt.Errorf("header needs a plain_text text")
+		}
+		return validateSlackText(*b.Text, notification.SlackMaxHeaderLength)
+	case "section":
+		if b.Text == nil && len(b.Fields) == 0 {
+			return fmt.Errorf("section needs a text or fields")
+		}
+		if b.Text != nil {
+			if err := validateSlackText(*b.Text, notification.SlackMaxTextLength); err != nil {
+				return err
+			}
+		}
+		if len(b.Fields) > notification.SlackMaxFields {
+			return fmt.Errorf("section has %d fields, the limit is %d", len(b.Fields), notification.SlackMaxFields)
+		}
+		for _, f := range b.Fields {
+			if err := validateSlackText(f, notification.SlackMaxFieldLength); err != nil {
+				return err
+			}
+		}
+		if len(b.Accessory) > 0 {
+			return validateSlackElement(b.Accessory)
+		}
+	case "context":
+		if len(b.Elements) == 0 || len(b.Elements) > notification.SlackMaxContextItems {
+			return fmt.Errorf("context has %d elements, it needs 1 to %d", len(b.Elements), notification.SlackMaxContextItems)
+		}
+		for _, e := range b.Elements {
+			if err := validateSlackElement(e); err != nil {
+				return err
+			}
+		}
+	case "actions":
+		if len(b.Elements) == 0 || len(b.Elements) > notification.SlackMaxButtons {
+			return fmt.Errorf("actions has %d elements, it needs 1 to %d", len(b.Elements), notification.SlackMaxButtons)
+		}
+		for _, e := range b.Elements {
+			if err := validateSlackElement(e); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown block type %q", b.Type)
+	}
+	return nil
+}
+
+func validateSlackText(t SlackText, max int) error {
+	if t.Type != "mrkdwn" && t.Type != "plain_text" {
+		return fmt.Errorf("unknown text type %q", t.Type)
+	}
+	if t.Text == "" {
+		return fmt.Errorf("text is empty")
+	}
+	if n := utf8.RuneCountInString(t.Text); n > max {
+		return fmt.Errorf("text has %d characters, the limit is %d", n, max)
+	}
+	return nil
+}
+
+func validateSlackElement(raw json.RawMessage) error {
+	var e struct {
+		Type     string          `json:"type"`
+		Text     json.RawMessage `json:"text"`
+		ImageURL string          `json:"image_url"`
+		AltText  string          `json:"alt_text"`
+		URL      string          `json:"url"`
+		Style    string          `json:"style"`
+	}
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return err
+	}
+
+	switch e.Type {
+	case "mrkdwn", "plain_text":
+		var text SlackText
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return err
+		}
+		return validateSlackText(text, notification.SlackMaxTextLength)
+	case "image":
+		if e.ImageURL == "" || e.AltText == "" {
+			return fmt.Errorf("image needs an image_url and an alt_text")
+		}
+	case "button":
+		var text SlackText
+		if err := json.Unmarshal(e.Text, &text); err != nil || text.Type != "plain_text" {
+			return fmt.Errorf("button needs a plain_text text")
+		}
+		if err := validateSlackText(text, notification.SlackMaxButtonTextSize); err != nil {
+			return err
+		}
+		if e.Style != "" && e.Style != "primary" && e.Style != "danger" {
+			return fmt.Errorf("unknown button style %q", e.Style)
+		}
+	default:
+		return fmt.Errorf("unknown element type %q", e.Type)
+	}
+	return nil
+}
@@ -0,0 +1,613 @@
+package notification
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrOutboxFull   = errors.New("notification outbox is full")
+	ErrOutboxClosed = errors.New("notification outbox is closed")
+)
+
+const outboxSegmentExt = ".seg"
+
+type OutboxOption func(*outboxOptions)
+
+type outboxOptions struct {
+	segmentSize int64
+	maxBytes    int64
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	sendTimeout time.Duration
+	maxAttempts int
+	maxAge      time.Duration
+	handleErr   DeliveryErrorHandler
+}
+
+// WithOutboxSegmentSize sets the size at which a new segment file is started, the default is 1 MB
+func WithOutboxSegmentSize(bytes int64) OutboxOption {
+	return func(o *outboxOptions) {
+		o.segmentSize = bytes
+	}
+}
+
+// WithOutboxMaxBytes caps the disk usage of the outbox, the default is 64 MB.
+// Messages that don't fit after compaction are rejected with ErrOutboxFull.
+func WithOutboxMaxBytes(bytes int64) OutboxOption {
+	return func(o *outboxOptions) {
+		o.maxBytes = bytes
+	}
+}
+
+// WithOutboxRetryDelay sets the exponential backoff between delivery attempts, the default starts at one second
+// and is capped at one minute. A Retry-After given by the endpoint is used instead of the backoff, up to maxDelay.
+func WithOutboxRetryDelay(baseDelay, maxDelay time.Duration) OutboxOption {
+	return func(o *outboxOptions) {
+		o.baseDelay = baseDelay
+		o.maxDelay = maxDelay
+	}
+}
+
+// WithOutboxSendTimeout sets the timeout of each delivery attempt, the default is DefaultHTTPTimeout
+func WithOutboxSendTimeout(d time.Duration) OutboxOption {
+	return func(o *outboxOptions) {
+		o.sendTimeout = d
+	}
+}
+
+// WithOutboxMaxAttempts sets how many times delivery of a message is attempted before it is dropped,
+// the default is 0 which keeps retrying until the message is older than the max age
+func WithOutboxMaxAttempts(n int) OutboxOption {
+	return func(o *outboxOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// WithOutboxMaxAge sets how long after it was sent a message is dropped if it still can't be delivered,
+// the default is 24 hours. Zero keeps retrying for as long as the attempts allow.
+func WithOutboxMaxAge(d time.Duration) OutboxOption {
+	return func(o *outboxOptions) {
+		o.maxAge = d
+	}
+}
+
+// WithOutboxErrorHandler sets the function that is called when a message is rejected by the endpoint or
+// given up on, and dropped.
+// The default handler logs the error with the standard logger.
+func WithOutboxErrorHandler(f DeliveryErrorHandler) OutboxOption {
+	return func(o *outboxOptions) {
+		o.handleErr = f
+	}
+}
+
+// Outbox is a Sender that writes messages to an append-only log on disk before they are delivered,
+// so that they survive outages of the notification channel and restarts of the service.
+// Messages are delivered in order in the background. Temporary errors and network errors are retried until
+// the message is delivered or too old, other errors drop the message since retrying it would block the ones after it. The log is a directory of segment files, segments with only
+// delivered messages are removed and the log is compacted when it is opened and when it reaches its size cap.
+type Outbox struct {
+	dir  string
+	next Sender
+	opts outboxOptions
+
+	mu       sync.Mutex
+	segments []*outboxSegment
+	pending  []*outboxEntry
+	nextID   uint64
+	nextSeq  uint64
+	size     int64
+	closed   bool
+	wake     chan struct{}
+	abort    chan struct{}
+	done     chan struct{}
+}
+
+type outboxSegment struct {
+	seq     uint64
+	size    int64
+	pending int
+	// file is only open for the last segment, which is appended to
+	file *os.File
+}
+
+type outboxEntry struct {
+	id      uint64
+	msg     Message
+	queued  time.Time
+	segment *outboxSegment
+}
+
+// outboxRecord is a line in a segment, either a message or the acknowledgement of its delivery
+type outboxRecord struct {
+	ID      uint64         `json:"id"`
+	Ack     bool           `json:"ack,omitempty"`
+	Queued  time.Time      `json:"queued,omitempty"`
+	Message *outboxMessage `json:"message,omitempty"`
+}
+
+// outboxMessage is a Message that can be encoded, the error is kept as its text
+type outboxMessage struct {
+	Severity    Severity          `json:"severity"`
+	Title       string            `json:"title"`
+	Body        string            `json:"body,omitempty"`
+	Fields      []Field           `json:"fields,omitempty"`
+	Err         string            `json:"error,omitempty"`
+	Links       []Link            `json:"links,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Fingerprint string            `json:"fingerprint,omitempty"`
+	Resolved    bool              `json:"resolved,omitempty"`
+	Source      string            `json:"source,omitempty"`
+	Timestamp   time.Time         `json:"timestamp"`
+}
+
+// OpenOutbox opens the outbox in dir, creating the directory if it doesn't exist, and starts delivering
+// the messages that were not delivered before the outbox was last closed
+func OpenOutbox(dir string, next Sender, opts ...OutboxOption) (*Outbox, error) {
+	o := outboxOptions{
+		segmentSize: 1 << 20,
+		maxBytes:    64 << 20,
+		baseDelay:   time.Second,
+		maxDelay:    time.Minute,
+		sendTimeout: DefaultHTTPTimeout,
+		maxAge:      24 * time.Hour,
+		handleErr: func(msg Message, err error) {
+			log.Printf("notification: outbox dropped %q: %v", msg.Title, err)
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	ob := &Outbox{
+		dir:    dir,
+		next:   next,
+		opts:   o,
+		nextID: 1,
+		wake:   make(chan struct{}, 1),
+		abort:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := ob.replay(); err != nil {
+		return nil, err
+	}
+
+	go ob.work()
+	return ob, nil
+}
+
+// Send writes the message to the log, it is delivered in the background.
+// Only the values of the message are kept, not the context.
+func (o *Outbox) Send(_ context.Context, msg Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return ErrOutboxClosed
+	}
+
+	entry := &outboxEntry{id: o.nextID, msg: msg, queued: time.Now()}
+	line, err := json.Marshal(entry.record())
+	if err != nil {
+		return err
+	}
+
+	if o.size+int64(len(line))+1 > o.opts.maxBytes {
+		if err := o.compact(); err != nil {
+			return err
+		}
+		if o.size+int64(len(line))+1 > o.opts.maxBytes {
+			return ErrOutboxFull
+		}
+	}
+
+	segment, err := o.append(line)
+	if err != nil {
+		return err
+	}
+	o.nextID++
+	entry.segment = segment
+	segment.pending++
+	o.pending = append(o.pending, entry)
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Pending returns the number of messages that have not been delivered yet
+func (o *Outbox) Pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// Close stops accepting messages and waits until the pending messages are delivered.
+// If ctx is done first, delivery is stopped and the context error is returned. The messages that
+// were not delivered stay in the log and are delivered when the outbox is opened again.
+func (o *Outbox) Close(ctx context.Context) error {
+	o.mu.Lock()
+	if !o.closed {
+		o.closed = true
+		select {
+		case o.wake <- struct{}{}:
+		default:
+		}
+	}
+	o.mu.Unlock()
+
+	var err error
+	select {
+	case <-o.done:
+	case <-ctx.Done():
+		o.mu.Lock()
+		select {
+		case <-o.abort:
+		default:
+			close(o.abort)
+		}
+		o.mu.Unlock()
+		<-o.done
+		err = ctx.Err()
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if current := o.current(); current != nil && current.file != nil {
+		if closeErr := current.file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		current.file = nil
+	}
+	return err
+}
+
+func (o *Outbox) work() {
+	defer close(o.done)
+
+	attempt := 0
+	for {
+		o.mu.Lock()
+		if len(o.pending) == 0 {
+			closed := o.closed
+			o.mu.Unlock()
+			if closed {
+				return
+			}
+			select {
+			case <-o.wake:
+			case <-o.abort:
+				return
+			}
+			continue
+		}
+		entry := o.pending[0]
+		o.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), o.opts.sendTimeout)
+		err := o.next.Send(ctx, entry.msg)
+		cancel()
+
+		if err != nil && isRetryable(err) {
+			attempt++
+			if !o.expired(entry, attempt) {
+				timer := time.NewTimer(backoffDelay(attempt, o.opts.baseDelay, o.opts.maxDelay, err))
+				select {
+				case <-timer.C:
+				case <-o.abort:
+					timer.Stop()
+					return
+				}
+				continue
+			}
+			err = fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+
+		if err != nil {
+			o.opts.handleErr(entry.msg, err)
+		}
+		attempt = 0
+		o.ack(entry)
+	}
+}
+
+// isRetryable reports whether delivery may succeed later. Besides the temporary errors, all network errors are
+// retried since the endpoint may just be unreachable for now.
+func isRetryable(err error) bool {
+	if IsTemporary(err) {
+		return true
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// expired reports whether the outbox should give up on the entry after the failed attempt
+func (o *Outbox) expired(entry *outboxEntry, attempt int) bool {
+	if o.opts.maxAttempts > 0 && attempt >= o.opts.maxAttempts {
+		return true
+	}
+	return o.opts.maxAge > 0 && !entry.queued.IsZero() && time.Since(entry.queued) > o.opts.maxAge
+}
+
+// ack marks the entry as delivered and removes the oldest segments that have nothing left to deliver
+func (o *Outbox) ack(entry *outboxEntry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.pending = o.pending[1:]
+	entry.segment.pending--
+
+	if len(o.pending) == 0 {
+		// everything is delivered, start over with an empty log
+		if err := o.compact(); err != nil {
+			log.Printf("notification: could not compact the outbox: %v", err)
+		}
+		return
+	}
+
+	line, _ := json.Marshal(&outboxRecord{ID: entry.id, Ack: true})
+	if _, err := o.append(line); err != nil {
+		// the message is delivered again after a restart
+		log.Printf("notification: could not write to the outbox: %v", err)
+	}
+
+	// only the oldest segments are removed, a later segment may hold the acks of messages in an earlier one that
+	// is kept, and those messages would be delivered again after a restart
+	removed := 0
+	for _, s := range o.segments {
+		if s.pending > 0 || s == o.current() {
+			break
+		}
+		o.removeSegment(s)
+		o.size -= s.size
+		removed++
+	}
+	o.segments = o.segments[removed:]
+}
+
+func (o *Outbox) current() *outboxSegment {
+	if len(o.segments) == 0 {
+		return nil
+	}
+	return o.segments[len(o.segments)-1]
+}
+
+// append writes a line to the last segment, starting a new one if it is full
+func (o *Outbox) append(line []byte) (*outboxSegment, error) {
+	current := o.current()
+	if current == nil || current.file == nil || (current.size > 0 && current.size+int64(len(line))+1 > o.opts.segmentSize) {
+		var err error
+		if current, err = o.startSegment(); err != nil {
+			return nil, err
+		}
+	}
+
+	line = append(line, '\n')
+	n, err := current.file.Write(line)
+	current.size += int64(n)
+	o.size += int64(n)
+	if err != nil {
+		return nil, err
+	}
+	return current, current.file.Sync()
+}
+
+func (o *Outbox) startSegment() (*outboxSegment, error) {
+	if current := o.current(); current != nil && current.file != nil {
+		if err := current.file.Close(); err != nil {
+			return nil, err
+		}
+		current.file = nil
+	}
+
+	segment := &outboxSegment{seq: o.nextSeq}
+	file, err := os.OpenFile(o.segmentPath(segment.seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	o.nextSeq++
+	segment.file = file
+	o.segments = append(o.segments, segment)
+	return segment, nil
+}
+
+// compact writes the pending messages to a new segment and removes all other segments
+func (o *Outbox) compact() error {
+	var buf bytes.Buffer
+	for _, entry := range o.pending {
+		line, err := json.Marshal(entry.record())
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	segment := &outboxSegment{seq: o.nextSeq, pending: len(o.pending)}
+	path := o.segmentPath(segment.seq)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	o.nextSeq++
+
+	n, err := file.Write(buf.Bytes())
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	segment.file = file
+	segment.size = int64(n)
+
+	// the old segments are only removed once the new one is written, after a crash in between
+	// the messages are read from both and deduplicated by id
+	for _, s := range o.segments {
+		o.removeSegment(s)
+	}
+	for _, entry := range o.pending {
+		entry.segment = segment
+	}
+	o.segments = []*outboxSegment{segment}
+	o.size = segment.size
+	return nil
+}
+
+func (o *Outbox) removeSegment(s *outboxSegment) {
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+	if err := os.Remove(o.segmentPath(s.seq)); err != nil && !os.IsNotExist(err) {
+		log.Printf("notification: could not remove outbox segment: %v", err)
+	}
+}
+
+func (o *Outbox) segmentPath(seq uint64) string {
+	return filepath.Join(o.dir, fmt.Sprintf("%020d%s", seq, outboxSegmentExt))
+}
+
+// replay reads the segments in the directory and compacts the messages that were not delivered into a new segment
+func (o *Outbox) replay() error {
+	files, err := ioutil.ReadDir(o.dir)
+	if err != nil {
+		return err
+	}
+
+	entries := map[uint64]*outboxEntry{}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, outboxSegmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, outboxSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		if seq >= o.nextSeq {
+			o.nextSeq = seq + 1
+		}
+
+		segment := &outboxSegment{seq: seq, size: f.Size()}
+		o.segments = append(o.segments, segment)
+		o.size += segment.size
+		if err := o.readSegment(segment, entries); err != nil {
+			return err
+		}
+	}
+	sort.Slice(o.segments, func(i, j int) bool { return o.segments[i].seq < o.segments[j].seq })
+
+	for _, entry := range entries {
+		o.pending = append(o.pending, entry)
+		if entry.id >= o.nextID {
+			o.nextID = entry.id + 1
+		}
+	}
+	sort.Slice(o.pending, func(i, j int) bool { return o.pending[i].id < o.pending[j].id })
+
+	return o.compact()
+}
+
+func (o *Outbox) readSegment(segment *outboxSegment, entries map[uint64]*outboxEntry) error {
+	file, err := os.Open(o.segmentPath(segment.seq))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// a line without a newline was not completely written, e.g. because of a crash
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var record outboxRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			log.Printf("notification: skipping corrupt line %d of outbox segment %d: %v", n, segment.seq, err)
+			continue
+		}
+		if record.ID >= o.nextID {
+			o.nextID = record.ID + 1
+		}
+		if record.Ack {
+			delete(entries, record.ID)
+		} else if record.Message != nil {
+			entries[record.ID] = &outboxEntry{id: record.ID, msg: record.Message.message(), queued: record.Queued, segment: segment}
+		}
+	}
+}
+
+func (e *outboxEntry) record() *outboxRecord {
+	return &outboxRecord{ID: e.id, Queued: e.queued, Message: toOutboxMessage(e.msg)}
+}
+
+func toOutboxMessage(msg Message) *outboxMessage {
+	m := &outboxMessage{
+		Severity:    msg.Severity,
+		Title:       msg.Title,
+		Body:        msg.Body,
+		Fields:      msg.Fields,
+		Links:       msg.Links,
+		Labels:      msg.Labels,
+		Fingerprint: msg.Fingerprint,
+		Resolved:    msg.Resolved,
+		Source:      msg.Source,
+		Timestamp:   msg.Timestamp,
+	}
+	if msg.Err != nil {
+		m.Err = msg.Err.Error()
+	}
+	return m
+}
+
+func (m *outboxMessage) message() Message {
+	msg := Message{
+		Severity:    m.Severity,
+		Title:       m.Title,
+		Body:        m.Body,
+		Fields:      m.Fields,
+		Links:       m.Links,
+		Labels:      m.Labels,
+		Fingerprint: m.Fingerprint,
+		Resolved:    m.Resolved,
+		Source:      m.Source,
+		Timestamp:   m.Timestamp,
+	}
+	if m.Err != "" {
+		msg.Err = errors.New(m.Err)
+	}
+	return msg
+}
@@ -0,0 +1,264 @@
+package notification_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+	"github.com/SecuritasCrimePrediction/apitools-go/notification/notificationtest"
+)
+
+func outboxSize(t *testing.T, dir string) (files int, size int64) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, info := range infos {
+		files++
+		size += info.Size()
+	}
+	return files, size
+}
+
+func Test_Outbox_ReplayAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	unreachable := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	})
+
+	outbox, err := notification.OpenOutbox(dir, unreachable,
+		notification.WithOutboxRetryDelay(time.Millisecond, time.Millisecond),
+		notification.WithOutboxSegmentSize(300))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		msg := notification.Message{Severity: notification.SeverityAlert, Title: fmt.Sprintf("migration %d failed", i), Err: errors.New("duplicate key")}
+		if err := outbox.Send(context.Background(), msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if files, _ := outboxSize(t, dir); files < 2 {
+		t.Errorf("expected the log to be split into segments, got %d files", files)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := outbox.Close(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected the close to time out, got: %v", err)
+	}
+	if err := outbox.Send(context.Background(), notification.Message{}); err != notification.ErrOutboxClosed {
+		t.Errorf("expected ErrOutboxClosed, got: %v", err)
+	}
+
+	recorder := notificationtest.NewRecorder()
+	outbox, err = notification.OpenOutbox(dir, recorder)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msgs, ok := recorder.WaitForMessages(time.Second, 5, notificationtest.Any())
+	if !ok {
+		t.Fatalf("expected the messages to be delivered after the restart, got: %d", len(msgs))
+	}
+	for i, msg := range msgs {
+		if msg.Title != fmt.Sprintf("migration %d failed", i) || msg.Err == nil || msg.Err.Error() != "duplicate key" {
+			t.Errorf("unexpected message %d: %+v", i, msg)
+		}
+	}
+
+	if err := outbox.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files, size := outboxSize(t, dir); files != 1 || size != 0 {
+		t.Errorf("expected the delivered messages to be compacted away, got %d files of %d bytes", files, size)
+	}
+
+	// nothing is delivered twice
+	recorder.Reset()
+	outbox, _ = notification.OpenOutbox(dir, recorder)
+	_ = outbox.Close(context.Background())
+	if recorder.Len() != 0 {
+		t.Errorf("expected no messages, got: %v", recorder.Messages())
+	}
+}
+
+func Test_Outbox_AcksInLaterSegments(t *testing.T) {
+	dir := t.TempDir()
+	gates := map[string]chan struct{}{"first": make(chan struct{}), "second": make(chan struct{})}
+	sender := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		if gate, ok := gates[msg.Title]; ok {
+			<-gate
+			return nil
+		}
+		return &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	})
+	waitPending := func(outbox *notification.Outbox, n int) {
+		deadline := time.Now().Add(time.Second)
+		for outbox.Pending() != n && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if outbox.Pending() != n {
+			t.Fatalf("expected %d pending messages, got %d", n, outbox.Pending())
+		}
+	}
+
+	outbox, err := notification.OpenOutbox(dir, sender,
+		notification.WithOutboxRetryDelay(time.Millisecond, time.Millisecond),
+		notification.WithOutboxSegmentSize(1000))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the small messages share the first segment, every large one starts a segment of its own and so does
+	// every ack after one
+	large := strings.Repeat("x", 1200)
+	ctx := context.Background()
+	for _, msg := range []notification.Message{{Title: "first"}, {Title: "second"}, {Title: "third"}, {Title: "fourth", Body: large}} {
+		_ = outbox.Send(ctx, msg)
+	}
+	close(gates["first"])
+	waitPending(outbox, 3)
+	_ = outbox.Send(ctx, notification.Message{Title: "fifth", Body: large})
+	// the ack of the second message is written after the fifth, the segment with only the ack of the first
+	// can't be removed while the first segment still holds the third message
+	close(gates["second"])
+	waitPending(outbox, 3)
+
+	closeCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := outbox.Close(closeCtx); err != context.DeadlineExceeded {
+		t.Fatalf("expected the close to time out, got: %v", err)
+	}
+
+	recorder := notificationtest.NewRecorder()
+	outbox, err = notification.OpenOutbox(dir, recorder)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer outbox.Close(ctx)
+	msgs, ok := recorder.WaitForMessages(time.Second, 3, notificationtest.Any())
+	if !ok {
+		t.Fatalf("expected the undelivered messages after the restart, got: %d", len(msgs))
+	}
+	var titles []string
+	for _, msg := range msgs {
+		titles = append(titles, msg.Title)
+	}
+	if strings.Join(titles, ",") != "third,fourth,fifth" {
+		t.Errorf("expected only the undelivered messages, got: %v", titles)
+	}
+}
+
+func Test_Outbox_TornWriteAndRejected(t *testing.T) {
+	dir := t.TempDir()
+	segment := `{"id":1,"message":{"severity":2,"title":"first","timestamp":"2021-01-01T00:00:00Z"}}
+{"id":2,"message":{"severity":2,"title":"second","timestamp":"2021-01-01T00:00:00Z"}}
+{"id":4,"mess
+{"id":1,"ack":true}
+{"id":3,"message":{"sever`
+	if err := ioutil.WriteFile(filepath.Join(dir, "00000000000000000007.seg"), []byte(segment), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var dropped []notification.Message
+	rejecting := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		return &notification.StatusError{StatusCode: http.StatusBadRequest}
+	})
+	outbox, err := notification.OpenOutbox(dir, rejecting, notification.WithOutboxErrorHandler(func(msg notification.Message, err error) {
+		dropped = append(dropped, msg)
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := outbox.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dropped) != 1 || dropped[0].Title != "second" {
+		t.Errorf("expected only the unacknowledged message after the corrupt line to be dropped by the rejecting endpoint, got: %+v", dropped)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "00000000000000000007.seg")); !os.IsNotExist(err) {
+		t.Errorf("expected the old segment to be removed, got: %v", err)
+	}
+}
+
+func Test_Outbox_MaxBytes(t *testing.T) {
+	dir := t.TempDir()
+	blocked := make(chan struct{})
+	stuck := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		<-blocked
+		return nil
+	})
+
+	outbox, err := notification.OpenOutbox(dir, stuck, notification.WithOutboxMaxBytes(500))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		close(blocked)
+		_ = outbox.Close(context.Background())
+	}()
+
+	var full error
+	for i := 0; i < 10 && full == nil; i++ {
+		full = outbox.Send(context.Background(), notification.Message{Title: fmt.Sprintf("message %d", i)})
+	}
+	if full != notification.ErrOutboxFull {
+		t.Fatalf("expected ErrOutboxFull, got: %v", full)
+	}
+	if _, size := outboxSize(t, dir); size > 500 {
+		t.Errorf("expected at most 500 bytes on disk, got: %d", size)
+	}
+}
+
+func Test_Outbox_GivesUp(t *testing.T) {
+	var mu sync.Mutex
+	var dropped []error
+	handler := notification.WithOutboxErrorHandler(func(msg notification.Message, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		dropped = append(dropped, err)
+	})
+
+	recorder := notificationtest.NewRecorder()
+	failing := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		switch msg.Title {
+		case "invalid auth":
+			return &notification.SlackAPIError{Method: "chat.postMessage", Code: "invalid_auth"}
+		case "unavailable":
+			return &notification.StatusError{StatusCode: http.StatusServiceUnavailable}
+		}
+		return recorder.Send(ctx, msg)
+	})
+
+	outbox, err := notification.OpenOutbox(t.TempDir(), failing, handler,
+		notification.WithOutboxRetryDelay(time.Millisecond, time.Millisecond),
+		notification.WithOutboxMaxAttempts(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, title := range []string{"invalid auth", "unavailable", "delivered"} {
+		_ = outbox.Send(context.Background(), notification.Message{Title: title})
+	}
+	if err := outbox.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if recorder.Len() != 1 {
+		t.Errorf("expected the messages after the failing ones to be delivered, got: %v", recorder.Messages())
+	}
+	var apiErr *notification.SlackAPIError
+	var statusErr *notification.StatusError
+	if len(dropped) != 2 || !errors.As(dropped[0], &apiErr) || !errors.As(dropped[1], &statusErr) ||
+		!strings.Contains(dropped[1].Error(), "gave up after 3 attempts") {
+		t.Errorf("expected the permanent error and the exhausted retries to be dropped, got: %v", dropped)
+	}
+}
@@ -0,0 +1,166 @@
+package notification
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+)
+
+// DefaultPagerDutyEventsURL is the endpoint of the PagerDuty Events API v2
+const DefaultPagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// pagerDutySummaryLimit is the maximum length of an event summary
+const pagerDutySummaryLimit = 1024
+
+// PagerDutyEvent is an event of the PagerDuty Events API v2
+type PagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key,omitempty"`
+	Payload     *PagerDutyPayload `json:"payload,omitempty"`
+	Links       []PagerDutyLink   `json:"links,omitempty"`
+}
+
+type PagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text,omitempty"`
+}
+
+type PagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	Component     string                 `json:"component,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// PagerDuty triggers and resolves PagerDuty incidents through the Events API v2.
+// Messages with the same fingerprint are deduplicated into one incident by PagerDuty,
+// and a message marked as Resolved resolves the incident of its fingerprint.
+type PagerDuty struct {
+	routingKey  string
+	eventsURL   string
+	client      *http.Client
+	fingerprint FingerprintFunc
+}
+
+type PagerDutyOption func(*PagerDuty)
+
+// WithPagerDutyEventsURL replaces DefaultPagerDutyEventsURL, e.g. with a local stub in tests
+func WithPagerDutyEventsURL(url string) PagerDutyOption {
+	return func(p *PagerDuty) {
+		p.eventsURL = url
+	}
+}
+
+// WithPagerDutyHTTPClient sets the client used to post the events.
+// The default client has a timeout of DefaultHTTPTimeout.
+func WithPagerDutyHTTPClient(c *http.Client) PagerDutyOption {
+	return func(p *PagerDuty) {
+		p.client = c
+	}
+}
+
+// WithPagerDutyFingerprint sets how the dedup key of a message is computed, the default is DefaultFingerprint
+func WithPagerDutyFingerprint(f FingerprintFunc) PagerDutyOption {
+	return func(p *PagerDuty) {
+		p.fingerprint = f
+	}
+}
+
+// NewPagerDuty returns a PagerDuty sender for the integration with the given routing key
+func NewPagerDuty(routingKey string, opts ...PagerDutyOption) *PagerDuty {
+	p := &PagerDuty{
+		routingKey:  routingKey,
+		eventsURL:   DefaultPagerDutyEventsURL,
+		client:      defaultHTTPClient,
+		fingerprint: DefaultFingerprint,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Send triggers an incident for the message, or resolves it if the message is marked as Resolved
+func (p *PagerDuty) Send(ctx context.Context, msg Message) error {
+	if msg.Resolved {
+		return p.Resolve(ctx, msg)
+	}
+
+	timestamp := msg.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	source := msg.Source
+	if source == "" {
+		source, _ = os.Hostname()
+	}
+
+	summary := truncate(msg.Title, pagerDutySummaryLimit)
+
+	details := map[string]interface{}{}
+	if msg.Body != "" {
+		details["body"] = msg.Body
+	}
+	for _, f := range msg.Fields {
+		details[f.Key] = f.Value
+	}
+	if msg.Err != nil {
+		details["error"] = msg.Err.Error()
+	}
+	if len(msg.Labels) > 0 {
+		details["labels"] = msg.Labels
+	}
+
+	var links []PagerDutyLink
+	for _, l := range msg.Links {
+		links = append(links, PagerDutyLink{Href: l.URL, Text: l.Text})
+	}
+
+	return p.post(ctx, &PagerDutyEvent{
+		RoutingKey:  p.routingKey,
+		EventAction: "trigger",
+		Links:       links,
+		DedupKey:    p.fingerprint(msg),
+		Payload: &PagerDutyPayload{
+			Summary:       summary,
+			Source:        source,
+			Severity:      pagerDutySeverity(msg.Severity),
+			Timestamp:     timestamp.Format(time.RFC3339),
+			Component:     msg.Labels[LabelMethod],
+			Class:         msg.Labels[LabelCode],
+			CustomDetails: details,
+		},
+	})
+}
+
+// Resolve resolves the incident with the fingerprint of the message
+func (p *PagerDuty) Resolve(ctx context.Context, msg Message) error {
+	return p.post(ctx, &PagerDutyEvent{
+		RoutingKey:  p.routingKey,
+		EventAction: "resolve",
+		DedupKey:    p.fingerprint(msg),
+	})
+}
+
+func (p *PagerDuty) post(ctx context.Context, event *PagerDutyEvent) error {
+	return postJSON(ctx, p.client, p.eventsURL, event, nil)
+}
+
+// pagerDutySeverity maps the severity to the severities of the Events API, which are critical, error, warning and info
+func pagerDutySeverity(s Severity) string {
+	switch {
+	case s >= SeverityCritical:
+		return "critical"
+	case s >= SeverityError:
+		return "error"
+	case s >= SeverityWarning:
+		return "warning"
+	}
+	return "info"
+}
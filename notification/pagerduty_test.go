@@ -0,0 +1,91 @@
+package notification_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+func Test_PagerDuty(t *testing.T) {
+	var events []notification.PagerDutyEvent
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notification.PagerDutyEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"status":"success","dedup_key":"` + event.DedupKey + `"}`))
+	}))
+	defer stub.Close()
+
+	pd := notification.NewPagerDuty("routing-key", notification.WithPagerDutyEventsURL(stub.URL))
+	failure := notification.Message{
+		Severity: notification.SeverityAlert,
+		Title:    "Error occurred in a call to Get",
+		Err:      errors.New("database is down"),
+		Labels:   map[string]string{notification.LabelMethod: "Get", notification.LabelCode: "Internal"},
+		Source:   "test.Service",
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := pd.Send(context.Background(), failure); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	resolved := failure
+	resolved.Resolved = true
+	if err := pd.Send(context.Background(), resolved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got: %d", len(events))
+	}
+	trigger := events[0]
+	if trigger.RoutingKey != "routing-key" || trigger.EventAction != "trigger" || trigger.Payload.Severity != "error" ||
+		trigger.Payload.Summary != failure.Title || trigger.Payload.Source != "test.Service" || trigger.Payload.Component != "Get" {
+		t.Errorf("unexpected trigger event: %+v", trigger)
+	}
+	if events[1].DedupKey != trigger.DedupKey || events[2].DedupKey != trigger.DedupKey || trigger.DedupKey == "" {
+		t.Errorf("expected a stable dedup key, got: %q, %q and %q", trigger.DedupKey, events[1].DedupKey, events[2].DedupKey)
+	}
+	if events[2].EventAction != "resolve" || events[2].Payload != nil {
+		t.Errorf("unexpected resolve event: %+v", events[2])
+	}
+}
+
+func Test_PagerDuty_LongSummary(t *testing.T) {
+	var raw []byte
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer stub.Close()
+
+	pd := notification.NewPagerDuty("routing-key", notification.WithPagerDutyEventsURL(stub.URL))
+	// the limit falls in the middle of a multi-byte character
+	title := "x" + strings.Repeat("ö", 1100)
+	if err := pd.Send(context.Background(), notification.Message{Severity: notification.SeverityAlert, Title: title}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var event notification.PagerDutyEvent
+	if err := json.Unmarshal(raw, &event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	summary := event.Payload.Summary
+	if !utf8.ValidString(summary) || utf8.RuneCountInString(summary) != 1024 || !strings.HasSuffix(summary, "…") {
+		t.Errorf("expected the summary to be cut at 1024 characters, got %d characters", utf8.RuneCountInString(summary))
+	}
+}
@@ -0,0 +1,222 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frame is a function call in a goroutine stack
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+func (f Frame) String() string {
+	return f.Function + " (" + f.File + ":" + strconv.Itoa(f.Line) + ")"
+}
+
+// Package returns the import path of the package of the function
+func (f Frame) Package() string {
+	name := f.Function
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+// isStandardLibrary reports whether the frame is in the Go runtime or the standard library,
+// whose import paths have no dot in their first element
+func (f Frame) isStandardLibrary() bool {
+	pkg := f.Package()
+	if pkg == "main" {
+		return false
+	}
+	first := strings.SplitN(pkg, "/", 2)[0]
+	return !strings.Contains(first, ".")
+}
+
+// Panic is a Go panic parsed by ParsePanic
+type Panic struct {
+	// Value is the value the goroutine panicked with
+	Value string
+	// Goroutine is the header of the stack of the panicking goroutine, e.g. "goroutine 1 [running]"
+	Goroutine string
+	// Frames are the calls of the panicking goroutine, the innermost first
+	Frames []Frame
+	// Stack is the stack of the panicking goroutine as it was printed
+	Stack string
+}
+
+// AppFrame returns the innermost frame below the call to panic that isn't in the Go runtime or the standard library,
+// which is usually where the panic happened. It returns false if there is no such frame.
+func (p *Panic) AppFrame() (Frame, bool) {
+	frames := p.Frames
+	// a stack printed by a recovery handler starts with the handler, skip to the panicking function
+	for i, f := range frames {
+		if f.Function == "panic" || f.Function == "runtime.gopanic" {
+			frames = frames[i+1:]
+			break
+		}
+	}
+	for _, f := range frames {
+		if !f.isStandardLibrary() {
+			return f, true
+		}
+	}
+	return Frame{}, false
+}
+
+// ParsePanic parses the output of a Go panic, as printed by the runtime when a program crashes ("panic: ...")
+// or by a recovery handler ("panic recovered:" followed by the value and the output of debug.Stack).
+// Only the stack of the first goroutine is parsed, which is the panicking one in these outputs.
+// It returns false if the output is not a panic, output starting with "panic: " is only a panic if it has a stack.
+func ParsePanic(output string) (*Panic, bool) {
+	lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+
+	start := -1
+	recovered := false
+	var value []string
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "panic recovered:") {
+			start, recovered = i, true
+			if v := strings.TrimSpace(strings.TrimPrefix(trimmed, "panic recovered:")); v != "" {
+				value = append(value, v)
+			}
+			break
+		}
+		if strings.HasPrefix(line, "panic: ") {
+			start = i
+			value = append(value, strings.TrimSuffix(strings.TrimPrefix(line, "panic: "), " [recovered]"))
+			break
+		}
+	}
+	if start < 0 {
+		return nil, false
+	}
+
+	p := &Panic{}
+	i := start + 1
+	// the value continues until the stack or a blank line after it, a repanic is printed as "\tpanic: ..."
+	for ; i < len(lines) && !isGoroutineHeader(lines[i]); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			if len(value) > 0 {
+				break
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "panic: ") || strings.HasPrefix(line, "[signal ") {
+			continue
+		}
+		value = append(value, line)
+	}
+	p.Value = strings.Join(value, "\n")
+
+	for ; i < len(lines) && !isGoroutineHeader(lines[i]); i++ {
+	}
+	if i == len(lines) {
+		// the runtime always prints a stack, without one "panic: " is just text
+		return p, recovered
+	}
+
+	p.Goroutine = strings.TrimSuffix(strings.TrimSpace(lines[i]), ":")
+	stack := []string{lines[i]}
+	for i++; i+1 < len(lines); i += 2 {
+		function, location := lines[i], lines[i+1]
+		if strings.TrimSpace(function) == "" || strings.HasPrefix(function, "created by ") ||
+			!strings.HasPrefix(location, "\t") {
+			break
+		}
+		stack = append(stack, function, location)
+		p.Frames = append(p.Frames, parseFrame(function, location))
+	}
+	p.Stack = strings.Join(stack, "\n")
+
+	return p, true
+}
+
+func isGoroutineHeader(line string) bool {
+	return strings.HasPrefix(line, "goroutine ") && strings.HasSuffix(strings.TrimSpace(line), ":")
+}
+
+// parseFrame parses a frame of a stack trace, e.g.
+// "main.(*T).run(0xc000010000, {0x4b1a2c, 0x3})" followed by "\t/src/main.go:12 +0x1d"
+func parseFrame(function, location string) Frame {
+	var f Frame
+	if strings.HasSuffix(function, ")") {
+		if paren := strings.LastIndex(function, "("); paren > 0 {
+			function = function[:paren]
+		}
+	}
+	f.Function = function
+
+	location = strings.TrimSpace(location)
+	if space := strings.LastIndex(location, " +0x"); space >= 0 {
+		location = location[:space]
+	}
+	if colon := strings.LastIndex(location, ":"); colon >= 0 {
+		f.File = location[:colon]
+		f.Line, _ = strconv.Atoi(location[colon+1:])
+	} else {
+		f.File = location
+	}
+	return f
+}
+
+// ErrorWriter writes to channel and sends an alert when a panic is written to it, see ParsePanic.
+// A panic has to be written in one call to Write, which is the case when it is logged.
+type ErrorWriter struct {
+	sender  Sender
+	channel io.Writer
+}
+
+// NewErrorWriter returns an ErrorWriter alerting about panics with the sender
+func NewErrorWriter(sender Sender, channel io.Writer) *ErrorWriter {
+	return &ErrorWriter{sender: sender, channel: channel}
+}
+
+// Write writes everything but panics to the channel as it is. Panics are written in red,
+// and sent as an alert with the panic value as the error and the stack as a code block.
+// Errors from sending the alert are ignored, so that writing doesn't fail.
+func (s ErrorWriter) Write(data []byte) (n int, err error) {
+	p, ok := ParsePanic(string(data))
+	if !ok {
+		return s.channel.Write(data)
+	}
+
+	_ = s.sender.Send(context.Background(), panicMessage(p, string(data)))
+	_, _ = fmt.Fprint(s.channel, "\n\x1b[31m"+string(data))
+	return len(data), nil
+}
+
+func panicMessage(p *Panic, output string) Message {
+	msg := Message{
+		Severity:  SeverityAlert,
+		Title:     "Panic",
+		Err:       errors.New(p.Value),
+		Timestamp: time.Now(),
+	}
+
+	if f, ok := p.AppFrame(); ok {
+		msg.Title = "Panic in " + f.Function
+		msg.Fields = append(msg.Fields, Field{Key: "Location", Value: f.File + ":" + strconv.Itoa(f.Line)})
+	}
+	if p.Goroutine != "" {
+		msg.Fields = append(msg.Fields, Field{Key: "Goroutine", Value: p.Goroutine})
+	}
+
+	stack := p.Stack
+	if stack == "" {
+		stack = strings.TrimSpace(output)
+	}
+	msg.Body = "```\n" + stack + "\n```"
+	return msg
+}
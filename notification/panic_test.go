@@ -0,0 +1,106 @@
+package notification_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+const runtimePanic = `panic: runtime error: index out of range [3] with length 2 [recovered]
+	panic: runtime error: index out of range [3] with length 2
+
+goroutine 7 [running]:
+panic({0x6b7f20, 0xc0000a8018})
+	/usr/local/go/src/runtime/panic.go:838 +0x207
+github.com/example/api/store.(*Store).Get(0xc000010000, {0x73a1c4, 0x3})
+	/src/api/store/store.go:42 +0x1d
+main.main()
+	/src/api/main.go:12 +0x25
+
+goroutine 1 [chan receive]:
+main.wait()
+	/src/api/main.go:20 +0x30
+`
+
+func Test_ParsePanic(t *testing.T) {
+	p, ok := notification.ParsePanic(runtimePanic)
+	if !ok {
+		t.Fatalf("expected a panic")
+	}
+	if p.Value != "runtime error: index out of range [3] with length 2" {
+		t.Errorf("unexpected value: %q", p.Value)
+	}
+	if p.Goroutine != "goroutine 7 [running]" || len(p.Frames) != 3 {
+		t.Fatalf("expected the frames of the panicking goroutine, got: %+v", p)
+	}
+	want := notification.Frame{Function: "github.com/example/api/store.(*Store).Get", File: "/src/api/store/store.go", Line: 42}
+	if f, ok := p.AppFrame(); !ok || f != want {
+		t.Errorf("expected app frame %+v, got: %+v", want, f)
+	}
+	if strings.Contains(p.Stack, "main.wait") {
+		t.Errorf("expected only the panicking goroutine in the stack, got: %s", p.Stack)
+	}
+
+	for _, output := range []string{
+		"",
+		"2021/01/02 15:04:05 listening on :8080\n",
+		"panic: this is just a log line\n",
+	} {
+		if _, ok := notification.ParsePanic(output); ok {
+			t.Errorf("expected %q not to be a panic", output)
+		}
+	}
+}
+
+func recoveredPanic() (output string) {
+	defer func() {
+		output = fmt.Sprintf("panic recovered:\n%v\n\n%s", recover(), debug.Stack())
+	}()
+	var m map[string]int
+	m["a"] = 1
+	return ""
+}
+
+func Test_ErrorWriter(t *testing.T) {
+	var sent []notification.Message
+	recorder := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		sent = append(sent, msg)
+		return nil
+	})
+
+	var out bytes.Buffer
+	w := notification.NewErrorWriter(recorder, &out)
+
+	if _, err := w.Write([]byte("just a line\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "just a line\n" || len(sent) != 0 {
+		t.Fatalf("expected the output to pass through, got: %q %v", out.String(), sent)
+	}
+
+	output := recoveredPanic()
+	if n, err := w.Write([]byte(output)); err != nil || n != len(output) {
+		t.Fatalf("unexpected result: %d %v", n, err)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("expected an alert, got: %v", sent)
+	}
+	msg := sent[0]
+	if msg.Severity != notification.SeverityAlert || msg.Err == nil || msg.Err.Error() != "assignment to entry in nil map" {
+		t.Errorf("unexpected alert: %+v", msg)
+	}
+	if msg.Title != "Panic in github.com/SecuritasCrimePrediction/apitools-go/notification_test.recoveredPanic" {
+		t.Errorf("expected the title to name the panicking function, got: %q", msg.Title)
+	}
+	if !strings.HasPrefix(msg.Body, "```\ngoroutine ") || !strings.HasSuffix(msg.Body, "\n```") {
+		t.Errorf("expected the stack as a code block, got: %q", msg.Body)
+	}
+	if !strings.Contains(out.String(), "assignment to entry in nil map") {
+		t.Errorf("expected the panic to be written, got: %q", out.String())
+	}
+}
@@ -0,0 +1,186 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// LabelEnvironment is the environment a message was sent from, routes can match on it
+const LabelEnvironment = "environment"
+
+// Match selects messages for a Route. Empty criteria match every message, the criteria that are set must all match.
+type Match struct {
+	// MinSeverity matches messages with at least this severity. The zero value is SeverityInfo,
+	// set it to SeverityDebug to match debug messages.
+	MinSeverity Severity
+	// Severities matches messages with one of these severities
+	Severities []Severity
+	// Labels matches messages that have all these labels with these values
+	Labels map[string]string
+	// Sources matches the source of the message, the patterns use the syntax of path.Match
+	Sources []string
+	// Methods matches the LabelMethod of the message, the patterns use the syntax of path.Match
+	Methods []string
+	// Environments matches the LabelEnvironment of the message, or the environment of the router
+	// or silencer if the label isn't set
+	Environments []string
+}
+
+// Route sends the messages it matches to its senders
+type Route struct {
+	Match   Match
+	Senders []Sender
+	// Stop ends the evaluation of the routes after this one when it matches
+	Stop bool
+}
+
+type RouterOption func(*Router)
+
+// WithDefaultSenders sets the senders of the messages that match no route
+func WithDefaultSenders(senders ...Sender) RouterOption {
+	return func(r *Router) {
+		r.defaults = senders
+	}
+}
+
+// WithRouterEnvironment sets the environment that routes match when a message has no LabelEnvironment
+func WithRouterEnvironment(environment string) RouterOption {
+	return func(r *Router) {
+		r.environment = environment
+	}
+}
+
+// Router is a Sender that sends messages to the senders of the routes they match.
+// The routes are evaluated in order, a message is sent to every matching route until a route with Stop matches.
+// A sender is only sent a message once, even if it is in several matching routes.
+type Router struct {
+	routes      []Route
+	defaults    []Sender
+	environment string
+}
+
+// NewRouter returns a Router with the routes
+func NewRouter(routes []Route, opts ...RouterOption) *Router {
+	r := &Router{routes: routes}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Send sends the message to the senders of the matching routes, or to the default senders if no route matches.
+// All the senders are tried, the errors are combined.
+func (r *Router) Send(ctx context.Context, msg Message) error {
+	senders := r.Senders(msg)
+
+	var errs []string
+	var last error
+	for _, sender := range senders {
+		if err := sender.Send(ctx, msg); err != nil {
+			errs = append(errs, err.Error())
+			last = err
+		}
+	}
+
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return last
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
+
+// Senders returns the senders the message is routed to
+func (r *Router) Senders(msg Message) []Sender {
+	var senders []Sender
+	matched := false
+	for _, route := range r.routes {
+		if !route.Match.matches(msg, r.environment) {
+			continue
+		}
+		matched = true
+		for _, sender := range route.Senders {
+			if !containsSender(senders, sender) {
+				senders = append(senders, sender)
+			}
+		}
+		if route.Stop {
+			break
+		}
+	}
+
+	if !matched {
+		return r.defaults
+	}
+	return senders
+}
+
+// matches reports whether msg matches m, environment is used when the message has no LabelEnvironment
+func (m Match) matches(msg Message, environment string) bool {
+	if msg.Severity < m.MinSeverity {
+		return false
+	}
+	if len(m.Severities) > 0 && !containsSeverity(m.Severities, msg.Severity) {
+		return false
+	}
+	for k, v := range m.Labels {
+		if value, ok := msg.Labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	if len(m.Sources) > 0 && !matchesAny(m.Sources, msg.Source) {
+		return false
+	}
+	if len(m.Methods) > 0 && !matchesAny(m.Methods, msg.Labels[LabelMethod]) {
+		return false
+	}
+	if len(m.Environments) > 0 {
+		if value, ok := msg.Labels[LabelEnvironment]; ok {
+			environment = value
+		}
+		if !matchesAny(m.Environments, environment) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSeverity(severities []Severity, severity Severity) bool {
+	for _, s := range severities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// containsSender compares the senders by identity, senders that aren't comparable (e.g. SenderFunc) are never equal
+func containsSender(senders []Sender, sender Sender) bool {
+	for _, s := range senders {
+		if sameSender(s, sender) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameSender(a, b Sender) bool {
+	// comparing interfaces holding uncomparable values panics
+	if !reflect.TypeOf(a).Comparable() || !reflect.TypeOf(b).Comparable() {
+		return false
+	}
+	return a == b
+}
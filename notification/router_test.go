@@ -0,0 +1,120 @@
+package notification_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+type namedSender struct {
+	name string
+	got  *[]string
+	err  error
+}
+
+func (s *namedSender) Send(ctx context.Context, msg notification.Message) error {
+	*s.got = append(*s.got, s.name)
+	return s.err
+}
+
+func Test_Router(t *testing.T) {
+	var got []string
+	pagerduty := &namedSender{name: "pagerduty", got: &got}
+	alerts := &namedSender{name: "alerts", got: &got}
+	info := &namedSender{name: "info", got: &got}
+	fallback := &namedSender{name: "fallback", got: &got}
+
+	router := notification.NewRouter([]notification.Route{
+		{
+			Match: notification.Match{
+				MinSeverity:  notification.SeverityAlert,
+				Sources:      []string{"*.DatabaseService"},
+				Environments: []string{"prod"},
+			},
+			Senders: []notification.Sender{pagerduty, alerts},
+		},
+		{
+			Match:   notification.Match{Severities: []notification.Severity{notification.SeverityAlert}},
+			Senders: []notification.Sender{alerts},
+		},
+		{
+			Match:   notification.Match{Methods: []string{"Health*"}},
+			Senders: []notification.Sender{},
+			Stop:    true,
+		},
+		{
+			Match:   notification.Match{Labels: map[string]string{"team": "data"}},
+			Senders: []notification.Sender{info},
+		},
+	}, notification.WithDefaultSenders(fallback), notification.WithRouterEnvironment("prod"))
+
+	tests := []struct {
+		name string
+		msg  notification.Message
+		want string
+	}{
+		{
+			name: "database alert in prod",
+			msg:  notification.Message{Severity: notification.SeverityAlert, Source: "api.DatabaseService"},
+			want: "pagerduty,alerts",
+		},
+		{
+			name: "database alert in dev",
+			msg: notification.Message{Severity: notification.SeverityAlert, Source: "api.DatabaseService",
+				Labels: map[string]string{notification.LabelEnvironment: "dev"}},
+			want: "alerts",
+		},
+		{
+			name: "stop",
+			msg: notification.Message{Severity: notification.SeverityInfo,
+				Labels: map[string]string{notification.LabelMethod: "HealthCheck", "team": "data"}},
+			want: "",
+		},
+		{
+			name: "labels",
+			msg:  notification.Message{Severity: notification.SeverityInfo, Labels: map[string]string{"team": "data"}},
+			want: "info",
+		},
+		{
+			name: "default",
+			msg:  notification.Message{Severity: notification.SeverityInfo, Source: "api.DatabaseService"},
+			want: "fallback",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			if err := router.Send(context.Background(), tt.msg); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("expected %q, got: %q", tt.want, strings.Join(got, ","))
+			}
+		})
+	}
+}
+
+func Test_Router_Errors(t *testing.T) {
+	var got []string
+	errA, errB := errors.New("a failed"), errors.New("b failed")
+	a := &namedSender{name: "a", got: &got, err: errA}
+	b := &namedSender{name: "b", got: &got, err: errB}
+	ok := &namedSender{name: "ok", got: &got}
+
+	router := notification.NewRouter([]notification.Route{{Senders: []notification.Sender{a, ok}}})
+	if err := router.Send(context.Background(), notification.Message{}); err != errA {
+		t.Errorf("expected the error of the failing sender, got: %v", err)
+	}
+
+	router = notification.NewRouter([]notification.Route{{Senders: []notification.Sender{a, ok, b}}})
+	err := router.Send(context.Background(), notification.Message{})
+	if err == nil || !strings.Contains(err.Error(), "a failed") || !strings.Contains(err.Error(), "b failed") {
+		t.Errorf("expected both errors, got: %v", err)
+	}
+	if strings.Join(got, ",") != "a,ok,a,ok,b" {
+		t.Errorf("expected every sender to be tried, got: %v", got)
+	}
+}
@@ -0,0 +1,324 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Redacted replaces the values of secrets when a config is rendered
+const Redacted = "[redacted]"
+
+// SecretResolver returns the current value of a secret, keyvaultx.KeyVault is one
+type SecretResolver interface {
+	GetSecretValue(ctx context.Context, name string) (string, error)
+}
+
+type SecretsOption func(*Secrets)
+
+// WithSecretsRefresh sets how often the secrets are resolved again, the default is five minutes
+func WithSecretsRefresh(d time.Duration) SecretsOption {
+	return func(s *Secrets) {
+		s.refresh = d
+	}
+}
+
+// WithSecretsClock replaces the system clock, used in tests
+func WithSecretsClock(c Clock) SecretsOption {
+	return func(s *Secrets) {
+		s.clock = c
+	}
+}
+
+// WithSecretsErrorHandler sets the function that is called when a secret could not be refreshed, the last value
+// stays in use. It is also called with an empty name when a SecretSender could not be built with the new value of a
+// secret, or its previous sender could not be closed.
+// The default handler logs the error with the standard logger.
+func WithSecretsErrorHandler(f func(name string, err error)) SecretsOption {
+	return func(s *Secrets) {
+		s.handleErr = f
+	}
+}
+
+// Secrets resolves secrets with a SecretResolver and resolves them again periodically, so that a rotated secret
+// is picked up without a restart. Use NewSecretSender to build senders with the secrets, closing the Secrets
+// doesn't close them.
+type Secrets struct {
+	resolver  SecretResolver
+	refresh   time.Duration
+	clock     Clock
+	handleErr func(name string, err error)
+
+	mu          sync.Mutex
+	values      map[string]string
+	subscribers []*secretSubscriber
+	closed      chan struct{}
+	done        chan struct{}
+}
+
+type secretSubscriber struct {
+	names    map[string]bool
+	onChange func()
+}
+
+// NewSecrets starts refreshing the secrets that are resolved with resolver
+func NewSecrets(resolver SecretResolver, opts ...SecretsOption) *Secrets {
+	s := &Secrets{
+		resolver: resolver,
+		refresh:  5 * time.Minute,
+		clock:    SystemClock(),
+		handleErr: func(name string, err error) {
+			log.Printf("notification: could not refresh secret %s: %v", name, err)
+		},
+		values: map[string]string{},
+		closed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	go s.run(s.clock.After(s.refresh))
+	return s
+}
+
+// Get returns the value of the secret, it is resolved the first time and refreshed from then on
+func (s *Secrets) Get(ctx context.Context, name string) (string, error) {
+	s.mu.Lock()
+	value, ok := s.values[name]
+	s.mu.Unlock()
+	if ok {
+		return value, nil
+	}
+
+	value, err := s.resolver.GetSecretValue(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve secret %s: %w", name, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[name] = value
+	return value, nil
+}
+
+// onChange calls f after a refresh that changed one of the secrets with the names
+func (s *Secrets) onChange(names []string, f func()) {
+	sub := &secretSubscriber{names: map[string]bool{}, onChange: f}
+	for _, name := range names {
+		sub.names[name] = true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, sub)
+}
+
+// Close stops refreshing the secrets, if ctx is done first the context error is returned
+func (s *Secrets) Close(ctx context.Context) error {
+	s.mu.Lock()
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Secrets) run(next <-chan time.Time) {
+	defer close(s.done)
+	for {
+		select {
+		case <-next:
+		case <-s.closed:
+			return
+		}
+
+		// the next refresh is scheduled before this one, so that a clock that is advanced after a refresh
+		// always triggers it
+		next = s.clock.After(s.refresh)
+		s.refreshAll()
+	}
+}
+
+// refreshAll resolves the secrets again and notifies the subscribers of the ones that changed
+func (s *Secrets) refreshAll() {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+	sort.Strings(names)
+
+	changed := map[string]bool{}
+	for _, name := range names {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultHTTPTimeout)
+		value, err := s.resolver.GetSecretValue(ctx, name)
+		cancel()
+		if err != nil {
+			s.handleErr(name, err)
+			continue
+		}
+
+		s.mu.Lock()
+		if s.values[name] != value {
+			s.values[name] = value
+			changed[name] = true
+		}
+		s.mu.Unlock()
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	subscribers := append([]*secretSubscriber(nil), s.subscribers...)
+	s.mu.Unlock()
+	for _, sub := range subscribers {
+		for name := range changed {
+			if sub.names[name] {
+				sub.onChange()
+				break
+			}
+		}
+	}
+}
+
+// SecretSender is a Sender built with secrets that is built again when one of the secrets changes
+type SecretSender struct {
+	secrets *Secrets
+	build   func(secret func(name string) string) (Sender, error)
+
+	mu      sync.RWMutex
+	current Sender
+	closed  bool
+	// closing counts the previous senders that are being closed in the background
+	closing sync.WaitGroup
+}
+
+// NewSecretSender builds a sender with build, which gets the values of the secrets it needs with secret:
+//
+//	slack, err := notification.NewSecretSender(ctx, secrets, func(secret func(string) string) (notification.Sender, error) {
+//		return notification.NewSlack(secret("slack-info-hook"), secret("slack-alert-hook"), environment, false), nil
+//	})
+//
+// When a refresh changes one of the secrets the sender is built again and replaces the previous one, which is closed
+// in the background if it has a Close(context.Context) error method. An error resolving a secret is returned.
+// The caller must Close the SecretSender, Build does it for the senders of a config.
+func NewSecretSender(ctx context.Context, secrets *Secrets, build func(secret func(name string) string) (Sender, error)) (*SecretSender, error) {
+	s := &SecretSender{secrets: secrets, build: build}
+	sender, names, err := s.buildWith(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.current = sender
+	secrets.onChange(names, s.rebuild)
+	return s, nil
+}
+
+// Send sends the message with the sender built with the current secrets
+func (s *SecretSender) Send(ctx context.Context, msg Message) error {
+	s.mu.RLock()
+	current := s.current
+	s.mu.RUnlock()
+	return current.Send(ctx, msg)
+}
+
+// Close stops building the sender again, closes the current sender if it has a Close(context.Context) error method
+// and waits for the previous senders that are being closed. If ctx is done first the context error is returned.
+func (s *SecretSender) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	current := s.current
+	s.mu.Unlock()
+
+	var err error
+	if c, ok := current.(interface{ Close(context.Context) error }); ok {
+		err = c.Close(ctx)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.closing.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	return err
+}
+
+// buildWith calls build and returns the names of the secrets it used
+func (s *SecretSender) buildWith(ctx context.Context) (Sender, []string, error) {
+	var names []string
+	var resolveErr error
+	sender, err := s.build(func(name string) string {
+		names = append(names, name)
+		value, err := s.secrets.Get(ctx, name)
+		if err != nil && resolveErr == nil {
+			resolveErr = err
+		}
+		return value
+	})
+	if resolveErr != nil {
+		return nil, nil, resolveErr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return sender, names, nil
+}
+
+func (s *SecretSender) rebuild() {
+	s.mu.RLock()
+	closed := s.closed
+	s.mu.RUnlock()
+	if closed {
+		return
+	}
+
+	sender, _, err := s.buildWith(context.Background())
+	if err != nil {
+		s.secrets.handleErr("", err)
+		return
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		// closed while building, the new sender is not used
+		s.mu.Unlock()
+		s.closeSender(sender)
+		return
+	}
+	previous := s.current
+	s.current = sender
+	s.closing.Add(1)
+	s.mu.Unlock()
+
+	go func() {
+		defer s.closing.Done()
+		s.closeSender(previous)
+	}()
+}
+
+// closeSender closes a sender that is no longer used if it has a Close(context.Context) error method
+func (s *SecretSender) closeSender(sender Sender) {
+	if c, ok := sender.(interface{ Close(context.Context) error }); ok {
+		if err := c.Close(context.Background()); err != nil {
+			s.secrets.handleErr("", fmt.Errorf("could not close the previous sender: %w", err))
+		}
+	}
+}
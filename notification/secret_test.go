@@ -0,0 +1,191 @@
+package notification_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+	"github.com/SecuritasCrimePrediction/apitools-go/notification/notificationtest"
+)
+
+// fakeVault is a SecretResolver with secrets that can be rotated
+type fakeVault struct {
+	mu       sync.Mutex
+	secrets  map[string]string
+	resolved int
+}
+
+func newFakeVault(secrets map[string]string) *fakeVault {
+	return &fakeVault{secrets: secrets}
+}
+
+func (v *fakeVault) GetSecretValue(ctx context.Context, name string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.resolved++
+	value, ok := v.secrets[name]
+	if !ok {
+		return "", errors.New("secret not found")
+	}
+	return value, nil
+}
+
+func (v *fakeVault) Set(name, value string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.secrets[name] = value
+}
+
+func Test_Secrets(t *testing.T) {
+	vault := newFakeVault(map[string]string{"hook": "https://hooks.example.com/1"})
+	clock := newFakeClock()
+	secrets := notification.NewSecrets(vault, notification.WithSecretsClock(clock), notification.WithSecretsRefresh(time.Minute))
+	defer secrets.Close(context.Background())
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if value, err := secrets.Get(ctx, "hook"); err != nil || value != "https://hooks.example.com/1" {
+			t.Errorf("unexpected secret: %s, %v", value, err)
+		}
+	}
+	if vault.resolved != 1 {
+		t.Errorf("expected the secret to be resolved once, got %d", vault.resolved)
+	}
+	if _, err := secrets.Get(ctx, "unknown"); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("expected an error naming the secret, got: %v", err)
+	}
+}
+
+func Test_SecretSender(t *testing.T) {
+	vault := newFakeVault(map[string]string{"hook": "https://hooks.example.com/1", "other": "unused"})
+	clock := newFakeClock()
+	secrets := notification.NewSecrets(vault, notification.WithSecretsClock(clock), notification.WithSecretsRefresh(time.Minute))
+	defer secrets.Close(context.Background())
+	ctx := context.Background()
+
+	built := make(chan string, 10)
+	recorders := map[string]*notificationtest.Recorder{}
+	var mu sync.Mutex
+	sender, err := notification.NewSecretSender(ctx, secrets, func(secret func(string) string) (notification.Sender, error) {
+		hook := secret("hook")
+		recorder := notificationtest.NewRecorder()
+		mu.Lock()
+		recorders[hook] = recorder
+		mu.Unlock()
+		built <- hook
+		return recorder, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-built
+	_, _ = secrets.Get(ctx, "other")
+
+	vault.Set("hook", "https://hooks.example.com/2")
+	vault.Set("other", "changed")
+	clock.Advance(time.Minute)
+	select {
+	case hook := <-built:
+		if hook != "https://hooks.example.com/2" {
+			t.Fatalf("expected the sender to be built with the rotated secret, got %s", hook)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the sender to be built again")
+	}
+	// the sender doesn't use the other secret, it is built once for the refresh
+	select {
+	case hook := <-built:
+		t.Errorf("unexpected build with %s", hook)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := sender.Send(ctx, notification.Message{Title: "deployed"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if recorders["https://hooks.example.com/1"].Len() != 0 || recorders["https://hooks.example.com/2"].Len() != 1 {
+		t.Errorf("expected the message to be sent with the rotated secret")
+	}
+
+	if _, err := notification.NewSecretSender(ctx, secrets, func(secret func(string) string) (notification.Sender, error) {
+		return notificationtest.NewRecorder(), errors.New(secret("missing"))
+	}); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected an error resolving the secret, got: %v", err)
+	}
+}
+
+// closingSender records that it was closed, closing it waits for release
+type closingSender struct {
+	*notificationtest.Recorder
+	release chan struct{}
+	closed  chan struct{}
+	err     error
+}
+
+func (s *closingSender) Close(ctx context.Context) error {
+	<-s.release
+	close(s.closed)
+	return s.err
+}
+
+func Test_SecretSender_Close(t *testing.T) {
+	vault := newFakeVault(map[string]string{"hook": "https://hooks.example.com/1"})
+	clock := newFakeClock()
+	errs := make(chan error, 10)
+	secrets := notification.NewSecrets(vault, notification.WithSecretsClock(clock), notification.WithSecretsRefresh(time.Minute),
+		notification.WithSecretsErrorHandler(func(name string, err error) {
+			errs <- err
+		}))
+	defer secrets.Close(context.Background())
+	ctx := context.Background()
+
+	built := make(chan *closingSender, 10)
+	sender, err := notification.NewSecretSender(ctx, secrets, func(secret func(string) string) (notification.Sender, error) {
+		s := &closingSender{
+			Recorder: notificationtest.NewRecorder(),
+			release:  make(chan struct{}),
+			closed:   make(chan struct{}),
+		}
+		if hook := secret("hook"); hook == "https://hooks.example.com/1" {
+			s.err = errors.New("could not drain " + hook)
+		}
+		built <- s
+		return s, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := <-built
+
+	vault.Set("hook", "https://hooks.example.com/2")
+	clock.Advance(time.Minute)
+	second := <-built
+	close(second.release)
+
+	// the first sender is still being closed in the background, closing waits for it
+	closeCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := sender.Close(closeCtx); err != context.DeadlineExceeded {
+		t.Errorf("expected the close to wait for the previous sender, got: %v", err)
+	}
+	select {
+	case <-second.closed:
+	default:
+		t.Errorf("expected the current sender to be closed")
+	}
+
+	close(first.release)
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "hooks.example.com/1") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected the error of closing the previous sender to be handled")
+	}
+}
@@ -0,0 +1,142 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Severity is the level of a notification, the zero value is SeverityInfo
+type Severity int
+
+const (
+	SeverityDebug Severity = iota - 1
+	SeverityInfo
+	SeverityNotice
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+
+	// SeverityAlert is the severity of the messages that need attention, senders with an info and an alert
+	// destination send the messages of this severity and above to the alert destination
+	SeverityAlert = SeverityError
+)
+
+var severityNames = map[Severity]string{
+	SeverityDebug:    "debug",
+	SeverityInfo:     "info",
+	SeverityNotice:   "notice",
+	SeverityWarning:  "warning",
+	SeverityError:    "error",
+	SeverityCritical: "critical",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// ParseSeverity returns the severity with the name returned by String, the case is ignored.
+// "alert" is accepted for SeverityAlert.
+func ParseSeverity(name string) (Severity, error) {
+	name = strings.ToLower(name)
+	if name == "alert" {
+		return SeverityAlert, nil
+	}
+	for s, n := range severityNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q", name)
+}
+
+// MarshalJSON encodes the severity as its name
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON decodes the name of a severity. The numbers 1 and 2 that info and alert were encoded as before
+// there were more severities are also accepted.
+func (s *Severity) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		switch number {
+		case 1:
+			*s = SeverityInfo
+		case 2:
+			*s = SeverityAlert
+		default:
+			return fmt.Errorf("unknown severity %d", number)
+		}
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	severity, err := ParseSeverity(name)
+	if err != nil {
+		return err
+	}
+	*s = severity
+	return nil
+}
+
+type severityStyle struct {
+	icon, alt, color string
+}
+
+var severityStyles = map[Severity]severityStyle{
+	SeverityDebug:    {icon: infoIconURL, alt: "debug", color: "#868e96"},
+	SeverityInfo:     {icon: infoIconURL, alt: "data", color: "#2eb67d"},
+	SeverityNotice:   {icon: infoIconURL, alt: "notice", color: "#36c5f0"},
+	SeverityWarning:  {icon: alertIconURL, alt: "warning", color: "#ecb22e"},
+	SeverityError:    {icon: alertIconURL, alt: "alert", color: "#d40e0d"},
+	SeverityCritical: {icon: alertIconURL, alt: "critical", color: "#7a0000"},
+}
+
+// style returns how the severity is shown, severities outside the range are shown as the nearest one
+func (s Severity) style() severityStyle {
+	switch {
+	case s < SeverityDebug:
+		s = SeverityDebug
+	case s > SeverityCritical:
+		s = SeverityCritical
+	}
+	return severityStyles[s]
+}
+
+// Color returns the colour of the severity as a hex RGB value, e.g. "#d40e0d" for SeverityError
+func (s Severity) Color() string {
+	return s.style().color
+}
+
+// IconURL returns the URL of the icon shown with messages of the severity
+func (s Severity) IconURL() string {
+	return s.style().icon
+}
+
+// SeverityFilter is a Sender that drops the messages below a minimum severity, e.g. to send only errors
+// to a pager. Resolved messages are always sent, they clear problems that may have been sent.
+type SeverityFilter struct {
+	next Sender
+	min  Severity
+}
+
+// NewSeverityFilter returns a SeverityFilter sending the messages of at least min to next
+func NewSeverityFilter(next Sender, min Severity) *SeverityFilter {
+	return &SeverityFilter{next: next, min: min}
+}
+
+// Send sends the message to next unless it is below the minimum severity
+func (f *SeverityFilter) Send(ctx context.Context, msg Message) error {
+	if msg.Severity < f.min && !msg.Resolved {
+		return nil
+	}
+	return f.next.Send(ctx, msg)
+}
@@ -0,0 +1,117 @@
+package notification_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+	"github.com/SecuritasCrimePrediction/apitools-go/notification/notificationtest"
+)
+
+func Test_Severity(t *testing.T) {
+	severities := []notification.Severity{
+		notification.SeverityDebug, notification.SeverityInfo, notification.SeverityNotice,
+		notification.SeverityWarning, notification.SeverityError, notification.SeverityCritical,
+	}
+	for i, s := range severities {
+		if i > 0 && s <= severities[i-1] {
+			t.Errorf("expected %v to be above %v", s, severities[i-1])
+		}
+		parsed, err := notification.ParseSeverity(strings.ToUpper(s.String()))
+		if err != nil || parsed != s {
+			t.Errorf("expected %v to parse, got %v, %v", s, parsed, err)
+		}
+		if s.Color() == "" || s.IconURL() == "" {
+			t.Errorf("expected a colour and an icon for %v", s)
+		}
+	}
+	if notification.INFO != notification.SeverityInfo || notification.ALERT != notification.SeverityAlert {
+		t.Errorf("expected the deprecated levels to keep their meaning")
+	}
+	if s, err := notification.ParseSeverity("alert"); err != nil || s != notification.SeverityAlert {
+		t.Errorf("expected alert to parse as SeverityAlert, got %v, %v", s, err)
+	}
+	if _, err := notification.ParseSeverity("urgent"); err == nil {
+		t.Errorf("expected an error for an unknown severity")
+	}
+	var zero notification.Message
+	if zero.Severity != notification.SeverityInfo {
+		t.Errorf("expected the zero severity to be info, got %v", zero.Severity)
+	}
+}
+
+func Test_Severity_JSON(t *testing.T) {
+	b, err := json.Marshal(struct{ S notification.Severity }{notification.SeverityWarning})
+	if err != nil || string(b) != `{"S":"warning"}` {
+		t.Errorf("expected the severity to be encoded by name, got %s, %v", b, err)
+	}
+
+	for data, want := range map[string]notification.Severity{
+		`"critical"`: notification.SeverityCritical,
+		`"alert"`:    notification.SeverityAlert,
+		// info and alert before there were more severities
+		`1`: notification.SeverityInfo,
+		`2`: notification.SeverityAlert,
+	} {
+		var s notification.Severity
+		if err := json.Unmarshal([]byte(data), &s); err != nil || s != want {
+			t.Errorf("expected %s to decode as %v, got %v, %v", data, want, s, err)
+		}
+	}
+	var s notification.Severity
+	if err := json.Unmarshal([]byte(`7`), &s); err == nil {
+		t.Errorf("expected an error for an unknown number")
+	}
+}
+
+func Test_SeverityFilter(t *testing.T) {
+	recorder := notificationtest.NewRecorder()
+	filter := notification.NewSeverityFilter(recorder, notification.SeverityWarning)
+	ctx := context.Background()
+
+	_ = filter.Send(ctx, notification.Message{Severity: notification.SeverityNotice, Title: "dropped"})
+	_ = filter.Send(ctx, notification.Message{Severity: notification.SeverityWarning, Title: "warning"})
+	_ = filter.Send(ctx, notification.Message{Severity: notification.SeverityCritical, Title: "critical"})
+	_ = filter.Send(ctx, notification.Message{Severity: notification.SeverityInfo, Title: "recovered", Resolved: true})
+
+	var titles []string
+	for _, msg := range recorder.Messages() {
+		titles = append(titles, msg.Title)
+	}
+	if strings.Join(titles, ",") != "warning,critical,recovered" {
+		t.Errorf("expected the messages below warning to be dropped except the resolved one, got: %v", titles)
+	}
+}
+
+func Test_Slack_SeverityHooks(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received[r.URL.Path] = string(body)
+	}))
+	defer server.Close()
+
+	slack := notification.NewSlack(server.URL+"/info", server.URL+"/alert", "prod", false,
+		notification.WithSlackSeverityHooks(map[notification.Severity]string{notification.SeverityWarning: server.URL + "/warning"}))
+	ctx := context.Background()
+	_ = slack.Send(ctx, notification.Message{Severity: notification.SeverityNotice, Title: "deployed"})
+	_ = slack.Send(ctx, notification.Message{Severity: notification.SeverityWarning, Title: "disk 80% full"})
+	_ = slack.Send(ctx, notification.Message{Severity: notification.SeverityCritical, Title: "disk full"})
+
+	mu.Lock()
+	defer mu.Unlock()
+	for path, alt := range map[string]string{"/info": `"alt_text":"notice"`, "/warning": `"alt_text":"warning"`, "/alert": `"alt_text":"critical"`} {
+		if !strings.Contains(received[path], alt) {
+			t.Errorf("expected %s to receive a message with %s, got: %s", path, alt, received[path])
+		}
+	}
+}
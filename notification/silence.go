@@ -0,0 +1,299 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LabelSilence is the ID of the silence an expiry report is about
+const LabelSilence = "silence"
+
+// ErrSilenceNotFound is returned for the ID of a silence that doesn't exist or already expired
+var ErrSilenceNotFound = errors.New("silence not found")
+
+// Silence suppresses the messages it matches between StartsAt and EndsAt, e.g. during planned maintenance
+type Silence struct {
+	// ID is set by the Silencer when the silence is added
+	ID    string
+	Match Match
+	// StartsAt is when the silence starts, the time it is added if it is zero
+	StartsAt  time.Time
+	EndsAt    time.Time
+	CreatedBy string
+	Comment   string
+	// Suppressed is the number of messages the silence suppressed so far
+	Suppressed int
+}
+
+// active reports whether the silence suppresses messages at t
+func (s *Silence) active(t time.Time) bool {
+	return !t.Before(s.StartsAt) && t.Before(s.EndsAt)
+}
+
+func (s *Silence) validate(now time.Time) error {
+	m := s.Match
+	if m.MinSeverity <= SeverityInfo && len(m.Severities) == 0 && len(m.Labels) == 0 && len(m.Sources) == 0 &&
+		len(m.Methods) == 0 && len(m.Environments) == 0 {
+		return errors.New("silence must match on at least one of severity, labels, source, method or environment")
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return errors.New("silence must end after it starts")
+	}
+	if !s.EndsAt.After(now) {
+		return errors.New("silence must end in the future")
+	}
+	if s.CreatedBy == "" {
+		return errors.New("silence must have a creator")
+	}
+	return nil
+}
+
+type SilencerOption func(*Silencer)
+
+// WithSilencerEnvironment sets the environment that silences match when a message has no LabelEnvironment
+func WithSilencerEnvironment(environment string) SilencerOption {
+	return func(s *Silencer) {
+		s.environment = environment
+	}
+}
+
+// WithSilencerSource sets the Source of the expiry reports, e.g. the name of the service
+func WithSilencerSource(source string) SilencerOption {
+	return func(s *Silencer) {
+		s.source = source
+	}
+}
+
+// WithSilencerResolution sets how often the silences are checked for expiry, the default is one second
+func WithSilencerResolution(d time.Duration) SilencerOption {
+	return func(s *Silencer) {
+		s.resolution = d
+	}
+}
+
+// WithSilencerClock replaces the system clock, used in tests
+func WithSilencerClock(c Clock) SilencerOption {
+	return func(s *Silencer) {
+		s.clock = c
+	}
+}
+
+// WithSilencerErrorHandler sets the function that is called when an expiry report could not be sent.
+// The default handler logs the error with the standard logger.
+func WithSilencerErrorHandler(f DeliveryErrorHandler) SilencerOption {
+	return func(s *Silencer) {
+		s.handleErr = f
+	}
+}
+
+// Silencer is a Sender that drops the messages matched by an active silence and sends the rest and all Resolved
+// messages to next.
+// When a silence expires a report with the number of messages it suppressed is sent to next.
+type Silencer struct {
+	next        Sender
+	environment string
+	source      string
+	resolution  time.Duration
+	clock       Clock
+	handleErr   DeliveryErrorHandler
+
+	mu       sync.Mutex
+	silences map[string]*Silence
+	closed   chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewSilencer starts a Silencer sending to next
+func NewSilencer(next Sender, opts ...SilencerOption) *Silencer {
+	s := &Silencer{
+		next:       next,
+		resolution: time.Second,
+		clock:      SystemClock(),
+		handleErr: func(msg Message, err error) {
+			log.Printf("notification: could not send %q: %v", msg.Title, err)
+		},
+		silences: map[string]*Silence{},
+		closed:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	go s.run(s.clock.After(s.resolution))
+	return s
+}
+
+// Add validates the silence and adds it with a new ID, the added silence is returned
+func (s *Silencer) Add(silence Silence) (Silence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+	if err := silence.validate(now); err != nil {
+		return Silence{}, err
+	}
+	silence.ID = uuid.New().String()
+	silence.Suppressed = 0
+	s.silences[silence.ID] = &silence
+	return silence, nil
+}
+
+// Expire ends the silence now, its report is sent at the next check
+func (s *Silencer) Expire(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	silence, ok := s.silences[id]
+	if !ok {
+		return ErrSilenceNotFound
+	}
+	now := s.clock.Now()
+	if silence.StartsAt.After(now) {
+		silence.StartsAt = now
+	}
+	if silence.EndsAt.After(now) {
+		silence.EndsAt = now
+	}
+	return nil
+}
+
+// Silences returns the silences that haven't expired yet, ordered by when they start
+func (s *Silencer) Silences() []Silence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	silences := make([]Silence, 0, len(s.silences))
+	for _, silence := range s.silences {
+		silences = append(silences, *silence)
+	}
+	sort.Slice(silences, func(i, j int) bool {
+		if !silences[i].StartsAt.Equal(silences[j].StartsAt) {
+			return silences[i].StartsAt.Before(silences[j].StartsAt)
+		}
+		return silences[i].ID < silences[j].ID
+	})
+	return silences
+}
+
+// Send drops the message if an active silence matches it, otherwise it is sent to next.
+// A message is counted by every active silence that matches it. Resolved messages are always sent, so that a
+// problem from before a silence can clear during it.
+func (s *Silencer) Send(ctx context.Context, msg Message) error {
+	if !msg.Resolved && s.suppress(msg) {
+		return nil
+	}
+	return s.next.Send(ctx, msg)
+}
+
+func (s *Silencer) suppress(msg Message) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	suppressed := false
+	for _, silence := range s.silences {
+		if silence.active(now) && silence.Match.matches(msg, s.environment) {
+			silence.Suppressed++
+			suppressed = true
+		}
+	}
+	return suppressed
+}
+
+// Close stops checking the silences and waits for the reports that are being sent, the silences stay active.
+// If ctx is done first the context error is returned.
+func (s *Silencer) Close(ctx context.Context) error {
+	s.mu.Lock()
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		<-s.done
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Silencer) run(next <-chan time.Time) {
+	defer close(s.done)
+	for {
+		select {
+		case <-next:
+		case <-s.closed:
+			return
+		}
+
+		// the next check is scheduled before this one, so that a clock that is advanced after a report
+		// was sent always triggers it
+		next = s.clock.After(s.resolution)
+		s.check()
+	}
+}
+
+// check removes the expired silences and sends their reports
+func (s *Silencer) check() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	for id, silence := range s.silences {
+		if now.Before(silence.EndsAt) {
+			continue
+		}
+		delete(s.silences, id)
+
+		msg := s.expired(silence, now)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if err := s.next.Send(context.Background(), msg); err != nil {
+				s.handleErr(msg, err)
+			}
+		}()
+	}
+}
+
+func (s *Silencer) expired(silence *Silence, now time.Time) Message {
+	title := "Silence expired"
+	if silence.Comment != "" {
+		title += ": " + truncate(silence.Comment, 100)
+	}
+	return Message{
+		Severity: SeverityInfo,
+		Title:    title,
+		Body: fmt.Sprintf("Suppressed %d messages in %s", silence.Suppressed,
+			formatDuration(silence.EndsAt.Sub(silence.StartsAt).Round(time.Second))),
+		Fields: []Field{
+			{Key: "Created by", Value: silence.CreatedBy},
+			{Key: "Started", Value: silence.StartsAt.Format(time.RFC3339)},
+			{Key: "Ended", Value: silence.EndsAt.Format(time.RFC3339)},
+		},
+		Labels:    map[string]string{LabelSilence: silence.ID},
+		Source:    s.source,
+		Timestamp: now,
+	}
+}
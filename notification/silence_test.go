@@ -0,0 +1,154 @@
+package notification_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+	"github.com/SecuritasCrimePrediction/apitools-go/notification/notificationtest"
+)
+
+func Test_Silencer(t *testing.T) {
+	recorder := notificationtest.NewRecorder()
+	clock := newFakeClock()
+	silencer := notification.NewSilencer(recorder,
+		notification.WithSilencerClock(clock),
+		notification.WithSilencerEnvironment("prod"))
+	defer silencer.Close(context.Background())
+
+	ctx := context.Background()
+	start := clock.Now().Add(time.Hour)
+	silence, err := silencer.Add(notification.Silence{
+		Match: notification.Match{
+			MinSeverity:  notification.SeverityAlert,
+			Sources:      []string{"dbmigration*"},
+			Environments: []string{"prod"},
+		},
+		StartsAt:  start,
+		EndsAt:    start.Add(2 * time.Hour),
+		CreatedBy: "jane",
+		Comment:   "migrating the orders database",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if silence.ID == "" {
+		t.Errorf("expected the silence to get an ID")
+	}
+
+	alert := notification.Message{Severity: notification.SeverityAlert, Title: "migration failed", Source: "dbmigration-orders"}
+
+	// before the silence starts the alert is sent
+	_ = silencer.Send(ctx, alert)
+	clock.Advance(time.Hour)
+	_ = silencer.Send(ctx, alert)
+	_ = silencer.Send(ctx, alert)
+	_ = silencer.Send(ctx, notification.Message{Severity: notification.SeverityInfo, Title: "migrated", Source: "dbmigration-orders"})
+	_ = silencer.Send(ctx, notification.Message{Severity: notification.SeverityAlert, Title: "down", Source: "api"})
+	staging := alert
+	staging.Labels = map[string]string{notification.LabelEnvironment: "staging"}
+	_ = silencer.Send(ctx, staging)
+
+	titles := []string{}
+	for _, msg := range recorder.Messages() {
+		titles = append(titles, msg.Title)
+	}
+	if len(titles) != 4 || titles[0] != "migration failed" || titles[1] != "migrated" || titles[2] != "down" || titles[3] != "migration failed" {
+		t.Fatalf("expected only the alerts of the source to be suppressed, got: %v", titles)
+	}
+	if silences := silencer.Silences(); len(silences) != 1 || silences[0].Suppressed != 2 {
+		t.Errorf("expected the silence to count the suppressed messages, got: %+v", silences)
+	}
+
+	clock.Advance(2 * time.Hour)
+	report, ok := recorder.WaitForMessage(time.Second, notificationtest.WithTitle("Silence expired: migrating the orders database"))
+	if !ok {
+		t.Fatalf("expected a report when the silence expires, got: %v", recorder.Messages())
+	}
+	if report.Body != "Suppressed 2 messages in 2h" || report.Labels[notification.LabelSilence] != silence.ID {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if len(silencer.Silences()) != 0 {
+		t.Errorf("expected the expired silence to be removed")
+	}
+
+	_ = silencer.Send(ctx, alert)
+	if recorder.Len() != 6 {
+		t.Errorf("expected the alert to be sent after the silence expired, got: %v", recorder.Messages())
+	}
+}
+
+func Test_Silencer_Expire(t *testing.T) {
+	recorder := notificationtest.NewRecorder()
+	clock := newFakeClock()
+	silencer := notification.NewSilencer(recorder, notification.WithSilencerClock(clock))
+	defer silencer.Close(context.Background())
+
+	silence, err := silencer.Add(notification.Silence{
+		Match:     notification.Match{Labels: map[string]string{"job": "import"}},
+		EndsAt:    clock.Now().Add(time.Hour),
+		CreatedBy: "jane",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = silencer.Send(context.Background(), notification.Message{Title: "import failed", Labels: map[string]string{"job": "import"}})
+
+	if err := silencer.Expire("unknown"); err != notification.ErrSilenceNotFound {
+		t.Errorf("expected ErrSilenceNotFound, got: %v", err)
+	}
+	if err := silencer.Expire(silence.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clock.Advance(time.Second)
+	if report, ok := recorder.WaitForMessage(time.Second, notificationtest.WithTitle("Silence expired")); !ok || report.Body != "Suppressed 1 messages in 0s" {
+		t.Errorf("expected a report for the expired silence, got: %v", recorder.Messages())
+	}
+}
+
+func Test_Silencer_Invalid(t *testing.T) {
+	clock := newFakeClock()
+	silencer := notification.NewSilencer(notificationtest.NewRecorder(), notification.WithSilencerClock(clock))
+	defer silencer.Close(context.Background())
+
+	now := clock.Now()
+	for name, silence := range map[string]notification.Silence{
+		"matches everything": {EndsAt: now.Add(time.Hour), CreatedBy: "jane"},
+		"ends before start":  {Match: notification.Match{Sources: []string{"api"}}, StartsAt: now, EndsAt: now.Add(-time.Hour), CreatedBy: "jane"},
+		"already ended":      {Match: notification.Match{Sources: []string{"api"}}, StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour), CreatedBy: "jane"},
+		"no creator":         {Match: notification.Match{Sources: []string{"api"}}, EndsAt: now.Add(time.Hour)},
+	} {
+		if _, err := silencer.Add(silence); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func Test_Silencer_Resolved(t *testing.T) {
+	recorder := notificationtest.NewRecorder()
+	silencer := notification.NewSilencer(recorder)
+	defer silencer.Close(context.Background())
+
+	_, err := silencer.Add(notification.Silence{
+		Match:     notification.Match{Sources: []string{"orders"}},
+		EndsAt:    time.Now().Add(time.Hour),
+		CreatedBy: "jane",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	_ = silencer.Send(ctx, notification.Message{Severity: notification.SeverityAlert, Title: "db down", Source: "orders"})
+	// the problem was alerted before the silence, it clears during it
+	_ = silencer.Send(ctx, notification.Message{Title: "db down", Source: "orders", Resolved: true})
+
+	msgs := recorder.Messages()
+	if len(msgs) != 1 || !msgs[0].Resolved {
+		t.Errorf("expected only the resolved message to be sent, got: %v", msgs)
+	}
+	if silences := silencer.Silences(); len(silences) != 1 || silences[0].Suppressed != 1 {
+		t.Errorf("expected the resolved message not to be counted, got: %+v", silences)
+	}
+}
@@ -0,0 +1,133 @@
+package silenceadmin
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+type SilenceService struct {
+	log *zap.SugaredLogger
+
+	silencer *notification.Silencer
+	UnimplementedSilenceServiceServer
+}
+
+func NewSilenceService(log *zap.SugaredLogger, silencer *notification.Silencer) SilenceServiceServer {
+	return SilenceService{
+		log:      log,
+		silencer: silencer,
+	}
+}
+
+func (s SilenceService) CreateSilence(ctx context.Context, request *CreateSilenceRequest) (*CreateSilenceResponse, error) {
+	silence, err := fromProto(request.GetSilence())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	silence, err = s.silencer.Add(silence)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	s.log.Infof("%s created silence %s until %v: %s", silence.CreatedBy, silence.ID, silence.EndsAt, silence.Comment)
+	return &CreateSilenceResponse{Silence: toProto(silence)}, nil
+}
+
+func (s SilenceService) ListSilences(ctx context.Context, request *ListSilencesRequest) (*ListSilencesResponse, error) {
+	silences := s.silencer.Silences()
+
+	response := &ListSilencesResponse{Silences: make([]*Silence, 0, len(silences))}
+	for _, silence := range silences {
+		response.Silences = append(response.Silences, toProto(silence))
+	}
+	return response, nil
+}
+
+func (s SilenceService) ExpireSilence(ctx context.Context, request *ExpireSilenceRequest) (*ExpireSilenceResponse, error) {
+	err := s.silencer.Expire(request.GetId())
+
+	if errors.Is(err, notification.ErrSilenceNotFound) {
+		return nil, status.Errorf(codes.NotFound, "silence %q not found", request.GetId())
+	}
+	if err != nil {
+		s.log.Warnf("could not expire silence %s, err: %v", request.GetId(), err)
+		return nil, status.Error(codes.Internal, "could not expire silence")
+	}
+
+	s.log.Infof("expired silence %s", request.GetId())
+	return &ExpireSilenceResponse{}, nil
+}
+
+func fromProto(s *Silence) (notification.Silence, error) {
+	if s == nil {
+		return notification.Silence{}, errors.New("silence is required")
+	}
+	m := s.GetMatch()
+	silence := notification.Silence{
+		Match: notification.Match{
+			Labels:       m.GetLabels(),
+			Sources:      m.GetSources(),
+			Methods:      m.GetMethods(),
+			Environments: m.GetEnvironments(),
+		},
+		CreatedBy: s.GetCreatedBy(),
+		Comment:   s.GetComment(),
+	}
+
+	if name := m.GetMinSeverity(); name != "" {
+		severity, err := notification.ParseSeverity(name)
+		if err != nil {
+			return notification.Silence{}, err
+		}
+		silence.Match.MinSeverity = severity
+	}
+	for _, name := range m.GetSeverities() {
+		severity, err := notification.ParseSeverity(name)
+		if err != nil {
+			return notification.Silence{}, err
+		}
+		silence.Match.Severities = append(silence.Match.Severities, severity)
+	}
+
+	if s.GetStartsAt() != nil {
+		silence.StartsAt = s.GetStartsAt().AsTime()
+	}
+	if s.GetEndsAt() == nil {
+		return notification.Silence{}, errors.New("silence must have an end")
+	}
+	silence.EndsAt = s.GetEndsAt().AsTime()
+	return silence, nil
+}
+
+func toProto(s notification.Silence) *Silence {
+	m := &SilenceMatch{
+		Labels:       s.Match.Labels,
+		Sources:      s.Match.Sources,
+		Methods:      s.Match.Methods,
+		Environments: s.Match.Environments,
+	}
+	if s.Match.MinSeverity != notification.SeverityInfo {
+		m.MinSeverity = s.Match.MinSeverity.String()
+	}
+	for _, severity := range s.Match.Severities {
+		m.Severities = append(m.Severities, severity.String())
+	}
+
+	return &Silence{
+		Id:         s.ID,
+		Match:      m,
+		StartsAt:   timestamppb.New(s.StartsAt),
+		EndsAt:     timestamppb.New(s.EndsAt),
+		CreatedBy:  s.CreatedBy,
+		Comment:    s.Comment,
+		Suppressed: int64(s.Suppressed),
+	}
+}
@@ -0,0 +1,59 @@
+package silenceadmin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+	"github.com/SecuritasCrimePrediction/apitools-go/notification/notificationtest"
+)
+
+func Test_SilenceService(t *testing.T) {
+	silencer := notification.NewSilencer(notificationtest.NewRecorder())
+	defer silencer.Close(context.Background())
+	service := NewSilenceService(zap.NewNop().Sugar(), silencer)
+	ctx := context.Background()
+
+	created, err := service.CreateSilence(ctx, &CreateSilenceRequest{Silence: &Silence{
+		Match: &SilenceMatch{
+			MinSeverity: "alert",
+			Labels:      map[string]string{"database": "orders"},
+		},
+		EndsAt:    timestamppb.New(time.Now().Add(time.Hour)),
+		CreatedBy: "jane",
+		Comment:   "migration",
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Silence.Id == "" || created.Silence.StartsAt == nil || created.Silence.Match.MinSeverity != "error" {
+		t.Errorf("unexpected silence: %v", created.Silence)
+	}
+
+	list, err := service.ListSilences(ctx, &ListSilencesRequest{})
+	if err != nil || len(list.Silences) != 1 || list.Silences[0].Id != created.Silence.Id {
+		t.Errorf("expected the created silence, got: %v, %v", list, err)
+	}
+
+	if _, err := service.ExpireSilence(ctx, &ExpireSilenceRequest{Id: created.Silence.Id}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := service.ExpireSilence(ctx, &ExpireSilenceRequest{Id: "unknown"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got: %v", err)
+	}
+
+	_, err = service.CreateSilence(ctx, &CreateSilenceRequest{Silence: &Silence{
+		Match:     &SilenceMatch{Severities: []string{"urgent"}},
+		EndsAt:    timestamppb.New(time.Now().Add(time.Hour)),
+		CreatedBy: "jane",
+	}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for an unknown severity, got: %v", err)
+	}
+}
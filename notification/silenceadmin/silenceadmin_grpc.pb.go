@@ -0,0 +1,179 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package silenceadmin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// SilenceServiceClient is the client API for SilenceService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SilenceServiceClient interface {
+	// Create a silence, it suppresses the notifications it matches until it ends
+	CreateSilence(ctx context.Context, in *CreateSilenceRequest, opts ...grpc.CallOption) (*CreateSilenceResponse, error)
+	// List the silences that haven't expired
+	ListSilences(ctx context.Context, in *ListSilencesRequest, opts ...grpc.CallOption) (*ListSilencesResponse, error)
+	// End a silence now
+	ExpireSilence(ctx context.Context, in *ExpireSilenceRequest, opts ...grpc.CallOption) (*ExpireSilenceResponse, error)
+}
+
+type silenceServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSilenceServiceClient(cc grpc.ClientConnInterface) SilenceServiceClient {
+	return &silenceServiceClient{cc}
+}
+
+func (c *silenceServiceClient) CreateSilence(ctx context.Context, in *CreateSilenceRequest, opts ...grpc.CallOption) (*CreateSilenceResponse, error) {
+	out := new(CreateSilenceResponse)
+	err := c.cc.Invoke(ctx, "/sis.rp.dev.v1.SilenceService/CreateSilence", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *silenceServiceClient) ListSilences(ctx context.Context, in *ListSilencesRequest, opts ...grpc.CallOption) (*ListSilencesResponse, error) {
+	out := new(ListSilencesResponse)
+	err := c.cc.Invoke(ctx, "/sis.rp.dev.v1.SilenceService/ListSilences", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *silenceServiceClient) ExpireSilence(ctx context.Context, in *ExpireSilenceRequest, opts ...grpc.CallOption) (*ExpireSilenceResponse, error) {
+	out := new(ExpireSilenceResponse)
+	err := c.cc.Invoke(ctx, "/sis.rp.dev.v1.SilenceService/ExpireSilence", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SilenceServiceServer is the server API for SilenceService service.
+// All implementations must embed UnimplementedSilenceServiceServer
+// for forward compatibility
+type SilenceServiceServer interface {
+	// Create a silence, it suppresses the notifications it matches until it ends
+	CreateSilence(context.Context, *CreateSilenceRequest) (*CreateSilenceResponse, error)
+	// List the silences that haven't expired
+	ListSilences(context.Context, *ListSilencesRequest) (*ListSilencesResponse, error)
+	// End a silence now
+	ExpireSilence(context.Context, *ExpireSilenceRequest) (*ExpireSilenceResponse, error)
+	mustEmbedUnimplementedSilenceServiceServer()
+}
+
+// UnimplementedSilenceServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedSilenceServiceServer struct {
+}
+
+func (UnimplementedSilenceServiceServer) CreateSilence(context.Context, *CreateSilenceRequest) (*CreateSilenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSilence not implemented")
+}
+func (UnimplementedSilenceServiceServer) ListSilences(context.Context, *ListSilencesRequest) (*ListSilencesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSilences not implemented")
+}
+func (UnimplementedSilenceServiceServer) ExpireSilence(context.Context, *ExpireSilenceRequest) (*ExpireSilenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExpireSilence not implemented")
+}
+func (UnimplementedSilenceServiceServer) mustEmbedUnimplementedSilenceServiceServer() {}
+
+// UnsafeSilenceServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SilenceServiceServer will
+// result in compilation errors.
+type UnsafeSilenceServiceServer interface {
+	mustEmbedUnimplementedSilenceServiceServer()
+}
+
+func RegisterSilenceServiceServer(s grpc.ServiceRegistrar, srv SilenceServiceServer) {
+	s.RegisterService(&SilenceService_ServiceDesc, srv)
+}
+
+func _SilenceService_CreateSilence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSilenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SilenceServiceServer).CreateSilence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sis.rp.dev.v1.SilenceService/CreateSilence",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SilenceServiceServer).CreateSilence(ctx, req.(*CreateSilenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SilenceService_ListSilences_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSilencesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SilenceServiceServer).ListSilences(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sis.rp.dev.v1.SilenceService/ListSilences",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SilenceServiceServer).ListSilences(ctx, req.(*ListSilencesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SilenceService_ExpireSilence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExpireSilenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SilenceServiceServer).ExpireSilence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sis.rp.dev.v1.SilenceService/ExpireSilence",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SilenceServiceServer).ExpireSilence(ctx, req.(*ExpireSilenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SilenceService_ServiceDesc is the grpc.ServiceDesc for SilenceService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SilenceService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sis.rp.dev.v1.SilenceService",
+	HandlerType: (*SilenceServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateSilence",
+			Handler:    _SilenceService_CreateSilence_Handler,
+		},
+		{
+			MethodName: "ListSilences",
+			Handler:    _SilenceService_ListSilences_Handler,
+		},
+		{
+			MethodName: "ExpireSilence",
+			Handler:    _SilenceService_ExpireSilence_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "notification/silenceadmin/silenceadmin.proto",
+}
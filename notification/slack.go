@@ -1,17 +1,32 @@
 package notification
 
 import (
-	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
+	"strings"
+	"sync"
+	"time"
 )
 
+// INFO and ALERT are the levels of the messages before there were more severities. They are typed so that they
+// keep meaning info and alert when they are used as the Severity of a message.
+//
+// Deprecated: use SeverityInfo and SeverityAlert.
 const (
-	INFO  = 1
-	ALERT = 2
+	INFO  = SeverityInfo
+	ALERT = SeverityAlert
+)
+
+const (
+	alertIconURL = "https://www.freeiconspng.com/uploads/message-alert-red-icon--message-types-icons--softiconsm-4.png"
+	infoIconURL  = "https://www.freeiconspng.com/uploads/light-bulb-icon---colorful-stickers-part-2-set-yellow-3.png"
+	testIconURL  = "http://icons.iconarchive.com/icons/streamlineicons/streamline-ux-free/1024/hacker-icon.png"
+	testBanner   = "THE ABOVE IS A TEST, DON'T MIND IT"
 )
 
 type ImageAccessory struct {
@@ -25,9 +40,12 @@ type Text struct {
 	Text string `json:"text"`
 }
 
+// Section is a Block Kit block, the fields in use depend on the type. Use Blocks to build them.
 type Section struct {
 	Type      string          `json:"type"`
 	Text      *Text           `json:"text,omitempty"`
+	Fields    []Text          `json:"fields,omitempty"`
+	Elements  []Element       `json:"elements,omitempty"`
 	Accessory *ImageAccessory `json:"accessory,omitempty"` // This is a pointer because the encoder won't omit it otherwise
 }
 
@@ -37,142 +55,171 @@ type Body struct {
 
 type Slack struct {
 	infohook, alerthook, environment string
+	severityHooks                    map[Severity]string
 	testMode                         bool
-}
+	client                           *http.Client
 
-type ErrorWriter struct {
-	slacker *Slack
-	channel io.Writer
+	// partsSent holds how many parts of a split message were posted before posting the next part failed,
+	// so that a retry of the message doesn't post them again
+	mu        sync.Mutex
+	partsSent map[string]int
 }
 
-func (s ErrorWriter) Write(data []byte) (n int, err error) {
-	re := regexp.MustCompile(`(?m)panic recovered:\n(.+)[\s\S]+\n\n(.+)`)
-	msg := string(data)
-	matches := re.FindAllStringSubmatchIndex(msg, -1)[0]
+// maxPartialMessages caps the split messages a Slack remembers the posted parts of
+const maxPartialMessages = 100
 
-	// Formatting the error message
-	fmtMsg := "*" + msg[matches[2]:matches[3]-1] + "*\n" + msg[matches[3]:matches[5]] +
-		"\n```" + msg[matches[5]:] + "```"
+type SlackOption func(*Slack)
 
-	_ = s.slacker.Alert("@here ERROR", fmtMsg)
-	_, _ = fmt.Fprint(s.channel, "\n\x1b[31m"+msg)
-	return len(msg), nil
+// WithSlackHTTPClient sets the client used to post to the webhooks.
+// The default client has a timeout of DefaultHTTPTimeout.
+func WithSlackHTTPClient(c *http.Client) SlackOption {
+	return func(s *Slack) {
+		s.client = c
+	}
 }
 
-func NewSlack(infohook, alerthook, environment string, testMode bool) *Slack {
-	return &Slack{
+// WithSlackSeverityHooks sets the webhooks of the severities that don't go to the info or alert hook,
+// e.g. a channel for warnings
+func WithSlackSeverityHooks(hooks map[Severity]string) SlackOption {
+	return func(s *Slack) {
+		s.severityHooks = hooks
+	}
+}
+
+func NewSlack(infohook, alerthook, environment string, testMode bool, opts ...SlackOption) *Slack {
+	s := &Slack{
 		infohook:    infohook,
 		alerthook:   alerthook,
 		environment: environment,
 		testMode:    testMode,
+		client:      defaultHTTPClient,
+		partsSent:   map[string]int{},
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
+// Writer returns an ErrorWriter alerting about panics with s
 func (s *Slack) Writer(channel io.Writer) *ErrorWriter {
-	return &ErrorWriter{slacker: s, channel: channel}
+	return NewErrorWriter(s, channel)
 }
 
+// Info sends an info message, it is a shorthand for Send with SeverityInfo
 func (s *Slack) Info(headline, msg string) error {
-	var body Body
-	if s.testMode {
-		body = formatTest(headline, msg, s.environment, INFO)
-	} else {
-		body = format(headline, msg, s.environment, INFO)
-	}
-	return s.send(body, s.infohook)
+	return s.Send(context.Background(), Message{Severity: SeverityInfo, Title: headline, Body: msg, Timestamp: time.Now()})
 }
 
+// Alert sends an alert message, it is a shorthand for Send with SeverityAlert
 func (s *Slack) Alert(headline, msg string) error {
-	var body Body
-	if s.testMode {
-		body = formatTest(headline, msg, s.environment, ALERT)
-	} else {
-		body = format(headline, msg, s.environment, ALERT)
-	}
-	return s.send(body, s.alerthook)
+	return s.Send(context.Background(), Message{Severity: SeverityAlert, Title: headline, Body: msg, Timestamp: time.Now()})
 }
 
-func (s *Slack) send(body Body, hook string) error {
-	marshal, err := json.Marshal(&body)
-	if err != nil {
-		return err
+// Send posts the message to the hook of its severity if there is one, otherwise to the alert hook
+// if it is an alert and to the info hook if it isn't. A message that is split into several parts and fails
+// part of the way is continued from the failed part when it is sent again, e.g. by a retry.
+func (s *Slack) Send(ctx context.Context, msg Message) error {
+	hook, ok := s.severityHooks[msg.Severity]
+	if !ok {
+		hook = s.infohook
+		if msg.Severity >= SeverityAlert {
+			hook = s.alerthook
+		}
 	}
 
-	_, err = http.Post(hook, "application/json", bytes.NewBuffer(marshal))
+	// a message that is too long for one Slack message is posted as several
+	bodies := formatBlocks(msg.Title, formatText(msg), s.environment, msg.Severity, s.testMode, msg.Links).Bodies()
+	if len(bodies) == 1 {
+		return s.send(ctx, bodies[0], hook)
+	}
+
+	key, err := partsKey(hook, bodies)
 	if err != nil {
 		return err
 	}
+	s.mu.Lock()
+	sent := s.partsSent[key]
+	s.mu.Unlock()
+
+	for i := sent; i < len(bodies); i++ {
+		if err := s.send(ctx, bodies[i], hook); err != nil {
+			s.mu.Lock()
+			if _, ok := s.partsSent[key]; !ok && len(s.partsSent) >= maxPartialMessages {
+				s.partsSent = map[string]int{}
+			}
+			s.partsSent[key] = i
+			s.mu.Unlock()
+			return err
+		}
+	}
+	s.mu.Lock()
+	delete(s.partsSent, key)
+	s.mu.Unlock()
 	return nil
 }
 
-func formatTest(headline, msg, environment string, level int) Body {
-	body := format(headline, msg, environment, level)
-
-	body.Blocks = append(body.Blocks, []Section{
-		{
-			Type: "divider",
-		},
-		{
-			Type: "section",
-			Text: &Text{
-				Type: "mrkdwn",
-				Text: "*THE ABOVE IS A TEST, DON'T MIND IT*",
-			},
-			Accessory: &ImageAccessory{
-				Type:     "image",
-				ImageUrl: "http://icons.iconarchive.com/icons/streamlineicons/streamline-ux-free/1024/hacker-icon.png",
-				AltText:  "testing",
-			},
-		},
-	}...)
-
-	return body
-}
-
-func format(headline, msg, environment string, level int) Body {
-	var image *ImageAccessory
-	switch level {
-	case ALERT:
-		image = &ImageAccessory{
-			Type:     "image",
-			ImageUrl: "https://www.freeiconspng.com/uploads/message-alert-red-icon--message-types-icons--softiconsm-4.png",
-			AltText:  "alert",
-		}
-	case INFO:
-		image = &ImageAccessory{
-			Type:     "image",
-			ImageUrl: "https://www.freeiconspng.com/uploads/light-bulb-icon---colorful-stickers-part-2-set-yellow-3.png",
-			AltText:  "data",
-		}
+// partsKey identifies the parts of a split message posted to the hook
+func partsKey(hook string, bodies []Body) (string, error) {
+	b, err := json.Marshal(bodies)
+	if err != nil {
+		return "", err
+	}
+	h := sha1.Sum(append([]byte(hook+"\n"), b...))
+	return hex.EncodeToString(h[:]), nil
+}
+
+// send posts the body to the hook, a *StatusError is returned if Slack doesn't accept it
+func (s *Slack) send(ctx context.Context, body Body, hook string) error {
+	return postJSON(ctx, s.client, hook, &body, nil)
+}
+
+// formatText renders the body, fields, error and source of a message as mrkdwn
+func formatText(msg Message) string {
+	var lines []string
+	if msg.Body != "" {
+		lines = append(lines, msg.Body)
+	}
+	for _, f := range msg.Fields {
+		lines = append(lines, fmt.Sprintf("*%s:* %s", f.Key, f.Value))
+	}
+	if msg.Err != nil {
+		lines = append(lines, fmt.Sprintf("*Error:* `%v`", msg.Err))
+	}
+	if msg.Source != "" {
+		lines = append(lines, fmt.Sprintf("*Source:* %s", msg.Source))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatBlocks lays out a message: the headline, the text with the icon of the severity, the links, the environment
+// and the test banner in test mode
+func formatBlocks(headline, text, environment string, severity Severity, testMode bool, links []Link) *Blocks {
+	style := severity.style()
+	image := &ImageAccessory{
+		Type:     "image",
+		ImageUrl: style.icon,
+		AltText:  style.alt,
 	}
 
-	return Body{
-		Blocks: []Section{
-			{
-				Type: "section",
-				Text: &Text{
-					Type: "mrkdwn",
-					Text: headline,
-				},
-			},
-			{
-				Type: "section",
-				Text: &Text{
-					Type: "mrkdwn",
-					Text: msg,
-				},
-				Accessory: image,
-			},
-			{
-				Type: "divider",
-			},
-			{
-				Type: "section",
-				Text: &Text{
-					Type: "mrkdwn",
-					Text: fmt.Sprintf("Environment: *%s*", environment),
-				},
-			},
-		},
+	b := NewBlocks()
+	if text == "" {
+		// Slack rejects sections without text
+		b.SectionWithImage(headline, image)
+	} else {
+		b.Section(headline).SectionWithImage(text, image)
+	}
+	if len(links) > 0 {
+		b.Buttons(links...)
+	}
+	b.Divider().Section(fmt.Sprintf("Environment: *%s*", environment))
+
+	if testMode {
+		b.Divider().SectionWithImage("*"+testBanner+"*", &ImageAccessory{
+			Type:     "image",
+			ImageUrl: testIconURL,
+			AltText:  "testing",
+		})
 	}
+	return b
 }
@@ -0,0 +1,289 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSlackAPIURL is the base URL of the Slack Web API
+const DefaultSlackAPIURL = "https://slack.com/api/"
+
+// SlackAPIError is returned when the Slack Web API responds with ok=false
+type SlackAPIError struct {
+	Method string
+	Code   string
+}
+
+func (e *SlackAPIError) Error() string {
+	return fmt.Sprintf("slack %s failed: %s", e.Method, e.Code)
+}
+
+// SlackAPI sends messages with a bot token through the Slack Web API.
+// The first message of a fingerprint is posted as a parent message, later messages with the same fingerprint
+// are posted as replies in its thread and the parent is updated with the number of occurrences.
+// A message marked as Resolved closes the thread and marks the parent as resolved.
+type SlackAPI struct {
+	token, infoChannel, alertChannel, environment string
+	severityChannels                              map[Severity]string
+	testMode                                      bool
+	baseURL                                       string
+	client                                        *http.Client
+	fingerprint                                   FingerprintFunc
+	threadTTL                                     time.Duration
+	handleErr                                     DeliveryErrorHandler
+
+	// mu protects threads, the messages of a fingerprint are serialized by the lock of its slackThreadLock
+	// so that they end up in the same thread without waiting for the messages of other fingerprints
+	mu      sync.Mutex
+	threads map[string]*slackThreadLock
+}
+
+type slackThreadLock struct {
+	mu sync.Mutex
+	// users is the number of senders holding or waiting for mu, it is protected by SlackAPI.mu
+	users  int
+	thread *slackThread
+}
+
+type slackThread struct {
+	channel string
+	ts      string
+	first   Message
+	count   int
+	started time.Time
+}
+
+type SlackAPIOption func(*SlackAPI)
+
+// WithSlackAPIURL replaces DefaultSlackAPIURL, e.g. with a fake Slack server in tests
+func WithSlackAPIURL(baseURL string) SlackAPIOption {
+	return func(s *SlackAPI) {
+		s.baseURL = strings.TrimSuffix(baseURL, "/") + "/"
+	}
+}
+
+// WithSlackAPIHTTPClient sets the client used to call the API.
+// The default client has a timeout of DefaultHTTPTimeout.
+func WithSlackAPIHTTPClient(c *http.Client) SlackAPIOption {
+	return func(s *SlackAPI) {
+		s.client = c
+	}
+}
+
+// WithSlackAPIFingerprint sets how messages are grouped into threads, the default is DefaultFingerprint
+func WithSlackAPIFingerprint(f FingerprintFunc) SlackAPIOption {
+	return func(s *SlackAPI) {
+		s.fingerprint = f
+	}
+}
+
+// WithSlackThreadTTL sets how long messages are added to the thread of their fingerprint, the default is 24 hours.
+// A message arriving after that starts a new thread.
+func WithSlackThreadTTL(d time.Duration) SlackAPIOption {
+	return func(s *SlackAPI) {
+		s.threadTTL = d
+	}
+}
+
+// WithSlackAPIErrorHandler sets the function that is called when the parent of a thread could not be updated after
+// a reply was posted. The message counts as sent, so the error is not returned by Send.
+// The default handler logs the error with the standard logger.
+func WithSlackAPIErrorHandler(f DeliveryErrorHandler) SlackAPIOption {
+	return func(s *SlackAPI) {
+		s.handleErr = f
+	}
+}
+
+// WithSlackAPISeverityChannels sets the channels of the severities that don't go to the info or alert channel,
+// e.g. a channel for warnings
+func WithSlackAPISeverityChannels(channels map[Severity]string) SlackAPIOption {
+	return func(s *SlackAPI) {
+		s.severityChannels = channels
+	}
+}
+
+// NewSlackAPI returns a SlackAPI posting info messages to infoChannel and alerts to alertChannel.
+// The bot token needs the chat:write scope.
+func NewSlackAPI(token, infoChannel, alertChannel, environment string, testMode bool, opts ...SlackAPIOption) *SlackAPI {
+	s := &SlackAPI{
+		token:        token,
+		infoChannel:  infoChannel,
+		alertChannel: alertChannel,
+		environment:  environment,
+		testMode:     testMode,
+		baseURL:      DefaultSlackAPIURL,
+		client:       defaultHTTPClient,
+		fingerprint:  DefaultFingerprint,
+		threadTTL:    24 * time.Hour,
+		handleErr: func(msg Message, err error) {
+			log.Printf("notification: could not update the slack thread of %q: %v", msg.Title, err)
+		},
+		threads: map[string]*slackThreadLock{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Info sends an info message, it is a shorthand for Send with SeverityInfo
+func (s *SlackAPI) Info(headline, msg string) error {
+	return s.Send(context.Background(), Message{Severity: SeverityInfo, Title: headline, Body: msg, Timestamp: time.Now()})
+}
+
+// Alert sends an alert message, it is a shorthand for Send with SeverityAlert
+func (s *SlackAPI) Alert(headline, msg string) error {
+	return s.Send(context.Background(), Message{Severity: SeverityAlert, Title: headline, Body: msg, Timestamp: time.Now()})
+}
+
+// Send posts the message as a new thread, or as a reply in the thread of its fingerprint
+func (s *SlackAPI) Send(ctx context.Context, msg Message) error {
+	fp := s.fingerprint(msg)
+	l := s.lock(fp)
+	defer s.unlock(fp, l)
+
+	if l.thread != nil && time.Since(l.thread.started) > s.threadTTL {
+		l.thread = nil
+	}
+
+	thread := l.thread
+	if thread == nil {
+		channel, ts, err := s.post(ctx, s.channel(msg), "", msg)
+		if err != nil {
+			return err
+		}
+		// a resolved message without a thread has nothing to resolve, it is posted on its own
+		if !msg.Resolved {
+			l.thread = &slackThread{channel: channel, ts: ts, first: msg, count: 1, started: time.Now()}
+		}
+		return nil
+	}
+
+	if _, _, err := s.post(ctx, thread.channel, thread.ts, msg); err != nil {
+		return err
+	}
+
+	var statusLine string
+	if msg.Resolved {
+		l.thread = nil
+		statusLine = fmt.Sprintf(":white_check_mark: *Resolved* after %d occurrences", thread.count)
+	} else {
+		thread.count++
+		statusLine = fmt.Sprintf("_Occurred %d times, latest at %s_", thread.count, time.Now().Format("2006-01-02 15:04:05 MST"))
+	}
+
+	// the reply is posted, so failing to update the parent must not make the caller send the message again
+	if err := s.update(ctx, thread, statusLine); err != nil {
+		s.handleErr(msg, err)
+	}
+	return nil
+}
+
+// lock locks the thread of the fingerprint, forgetting the threads that are too old to be continued
+func (s *SlackAPI) lock(fp string) *slackThreadLock {
+	s.mu.Lock()
+	for key, other := range s.threads {
+		if other.users == 0 && (other.thread == nil || time.Since(other.thread.started) > s.threadTTL) {
+			delete(s.threads, key)
+		}
+	}
+	l, ok := s.threads[fp]
+	if !ok {
+		l = &slackThreadLock{}
+		s.threads[fp] = l
+	}
+	l.users++
+	s.mu.Unlock()
+
+	l.mu.Lock()
+	return l
+}
+
+func (s *SlackAPI) unlock(fp string, l *slackThreadLock) {
+	l.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l.users--
+	if l.users == 0 && l.thread == nil {
+		delete(s.threads, fp)
+	}
+}
+
+func (s *SlackAPI) channel(msg Message) string {
+	if channel, ok := s.severityChannels[msg.Severity]; ok {
+		return channel
+	}
+	if msg.Severity >= SeverityAlert {
+		return s.alertChannel
+	}
+	return s.infoChannel
+}
+
+type slackAPIResponse struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error"`
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+}
+
+type slackChatMessage struct {
+	Channel  string    `json:"channel"`
+	TS       string    `json:"ts,omitempty"`
+	ThreadTS string    `json:"thread_ts,omitempty"`
+	Text     string    `json:"text"`
+	Blocks   []Section `json:"blocks"`
+}
+
+// post posts the message with chat.postMessage, in the thread of threadTS if it is set
+func (s *SlackAPI) post(ctx context.Context, channel, threadTS string, msg Message) (string, string, error) {
+	resp, err := s.call(ctx, "chat.postMessage", &slackChatMessage{
+		Channel:  channel,
+		ThreadTS: threadTS,
+		Text:     msg.Title,
+		Blocks:   s.format(msg.Title, formatText(msg), msg.Severity, msg.Links).Blocks,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return resp.Channel, resp.TS, nil
+}
+
+// update edits the parent message of the thread with chat.update, adding the status line to its text
+func (s *SlackAPI) update(ctx context.Context, thread *slackThread, statusLine string) error {
+	text := formatText(thread.first)
+	if text != "" {
+		text += "\n"
+	}
+	_, err := s.call(ctx, "chat.update", &slackChatMessage{
+		Channel: thread.channel,
+		TS:      thread.ts,
+		Text:    thread.first.Title,
+		Blocks:  s.format(thread.first.Title, text+statusLine, thread.first.Severity, thread.first.Links).Blocks,
+	})
+	return err
+}
+
+// format lays out the message like Slack does, truncated to one Slack message
+func (s *SlackAPI) format(headline, text string, severity Severity, links []Link) Body {
+	return formatBlocks(headline, text, s.environment, severity, s.testMode, links).Body()
+}
+
+func (s *SlackAPI) call(ctx context.Context, method string, payload interface{}) (*slackAPIResponse, error) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+s.token)
+
+	var resp slackAPIResponse
+	if err := callJSON(ctx, s.client, s.baseURL+method, payload, header, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.OK {
+		return nil, &SlackAPIError{Method: method, Code: resp.Error}
+	}
+	return &resp, nil
+}
@@ -0,0 +1,201 @@
+package notification_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+type slackAPICall struct {
+	Method   string
+	Token    string
+	Channel  string `json:"channel"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts"`
+	Text     string `json:"text"`
+	Blocks   json.RawMessage
+}
+
+// slackAPIServer stands in for the Slack Web API, it records the calls and answers with increasing timestamps
+func slackAPIServer(t *testing.T) (*httptest.Server, func() []slackAPICall) {
+	var mu sync.Mutex
+	var calls []slackAPICall
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var call slackAPICall
+		if err := json.NewDecoder(r.Body).Decode(&call); err != nil {
+			t.Errorf("could not decode payload: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		call.Method = strings.TrimPrefix(r.URL.Path, "/api/")
+		call.Token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		mu.Lock()
+		calls = append(calls, call)
+		ts := fmt.Sprintf("1600000000.%06d", len(calls))
+		mu.Unlock()
+
+		if call.Channel == "archived" {
+			_, _ = w.Write([]byte(`{"ok":false,"error":"is_archived"}`))
+			return
+		}
+		if call.TS != "" {
+			ts = call.TS
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "channel": call.Channel, "ts": ts})
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []slackAPICall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]slackAPICall(nil), calls...)
+	}
+}
+
+func Test_SlackAPI_Threads(t *testing.T) {
+	server, calls := slackAPIServer(t)
+	slack := notification.NewSlackAPI("xoxb-token", "C-INFO", "C-ALERT", "prod", false,
+		notification.WithSlackAPIURL(server.URL+"/api"))
+
+	ctx := context.Background()
+	msg := notification.Message{Severity: notification.SeverityAlert, Title: "db down", Err: errors.New("connection refused")}
+
+	for i := 0; i < 3; i++ {
+		if err := slack.Send(ctx, msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	got := calls()
+	// one parent, then a reply and an update of the parent for each repeat
+	if len(got) != 5 {
+		t.Fatalf("expected 5 calls, got %d: %+v", len(got), got)
+	}
+	parent := got[0]
+	if parent.Method != "chat.postMessage" || parent.Channel != "C-ALERT" || parent.ThreadTS != "" || parent.Token != "xoxb-token" {
+		t.Errorf("unexpected parent message: %+v", parent)
+	}
+	if parent.Text != "db down" || !strings.Contains(string(parent.Blocks), "connection refused") {
+		t.Errorf("expected the message in the parent, got: %+v", parent)
+	}
+
+	parentTS := "1600000000.000001"
+	for i, call := range got[1:] {
+		switch i % 2 {
+		case 0:
+			if call.Method != "chat.postMessage" || call.ThreadTS != parentTS {
+				t.Errorf("expected a thread reply, got: %+v", call)
+			}
+		case 1:
+			if call.Method != "chat.update" || call.TS != parentTS {
+				t.Errorf("expected an update of the parent, got: %+v", call)
+			}
+		}
+	}
+	if last := got[4]; !strings.Contains(string(last.Blocks), "Occurred 3 times") {
+		t.Errorf("expected the parent to show the count, got: %s", string(last.Blocks))
+	}
+
+	resolved := msg
+	resolved.Resolved = true
+	if err := slack.Send(ctx, resolved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got = calls()
+	if reply := got[5]; reply.ThreadTS != parentTS {
+		t.Errorf("expected the resolution in the thread, got: %+v", reply)
+	}
+	if update := got[6]; update.Method != "chat.update" || !strings.Contains(string(update.Blocks), "Resolved") {
+		t.Errorf("expected the parent to be marked resolved, got: %+v", update)
+	}
+
+	// the thread is closed, the next occurrence starts a new one
+	if err := slack.Send(ctx, msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if next := calls()[7]; next.Method != "chat.postMessage" || next.ThreadTS != "" {
+		t.Errorf("expected a new parent message, got: %+v", next)
+	}
+}
+
+func Test_SlackAPI_ChannelsAndErrors(t *testing.T) {
+	server, calls := slackAPIServer(t)
+	slack := notification.NewSlackAPI("token", "C-INFO", "archived", "dev", true,
+		notification.WithSlackAPIURL(server.URL+"/api/"))
+
+	if err := slack.Info("headline", "message"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info := calls()[0]
+	if info.Channel != "C-INFO" || !strings.Contains(string(info.Blocks), "THE ABOVE IS A TEST") {
+		t.Errorf("expected a test message in the info channel, got: %+v", info)
+	}
+
+	var apiErr *notification.SlackAPIError
+	err := slack.Alert("a", "b")
+	if !errors.As(err, &apiErr) || apiErr.Method != "chat.postMessage" || apiErr.Code != "is_archived" {
+		t.Errorf("expected a SlackAPIError, got: %v", err)
+	}
+}
+
+func Test_SlackAPI_SlowAndFailedUpdates(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var call slackAPICall
+		_ = json.NewDecoder(r.Body).Decode(&call)
+		switch {
+		case call.Channel == "C-SLOW":
+			<-release
+		case strings.HasSuffix(r.URL.Path, "chat.update"):
+			_, _ = w.Write([]byte(`{"ok":false,"error":"cant_update_message"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "channel": call.Channel, "ts": "1600000000.000001"})
+	}))
+	defer server.Close()
+	defer close(release)
+
+	var failed []error
+	slack := notification.NewSlackAPI("token", "C-SLOW", "C-ALERT", "prod", false,
+		notification.WithSlackAPIURL(server.URL),
+		notification.WithSlackAPIErrorHandler(func(msg notification.Message, err error) {
+			failed = append(failed, err)
+		}))
+
+	// a slow request doesn't hold up the messages of other fingerprints
+	go func() { _ = slack.Info("slow", "") }()
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan error)
+	go func() {
+		for i := 0; i < 2; i++ {
+			if err := slack.Alert("db down", ""); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected a failed update of the parent not to fail the reply, got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the alert not to wait for the slow info message")
+	}
+
+	var apiErr *notification.SlackAPIError
+	if len(failed) != 1 || !errors.As(failed[0], &apiErr) || apiErr.Method != "chat.update" {
+		t.Errorf("expected the failed update to be reported, got: %v", failed)
+	}
+}
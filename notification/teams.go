@@ -0,0 +1,202 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const adaptiveCardContentType = "application/vnd.microsoft.card.adaptive"
+
+// TeamsPayload is the message posted to a Teams incoming webhook
+type TeamsPayload struct {
+	Type        string            `json:"type"`
+	Attachments []TeamsAttachment `json:"attachments"`
+}
+
+type TeamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     AdaptiveCard `json:"content"`
+}
+
+type AdaptiveCard struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []CardElement `json:"body"`
+	Actions []CardAction  `json:"actions,omitempty"`
+	MSTeams *CardMSTeams  `json:"msteams,omitempty"`
+}
+
+// CardAction is an action of an AdaptiveCard, the links of a message are Action.OpenUrl actions
+type CardAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url,omitempty"`
+}
+
+type CardMSTeams struct {
+	Width string `json:"width"`
+}
+
+// CardElement is an element in the body of an AdaptiveCard, the fields in use depend on the type
+type CardElement struct {
+	Type      string        `json:"type"`
+	Text      string        `json:"text,omitempty"`
+	Weight    string        `json:"weight,omitempty"`
+	Size      string        `json:"size,omitempty"`
+	Color     string        `json:"color,omitempty"`
+	Style     string        `json:"style,omitempty"`
+	Wrap      bool          `json:"wrap,omitempty"`
+	Separator bool          `json:"separator,omitempty"`
+	URL       string        `json:"url,omitempty"`
+	AltText   string        `json:"altText,omitempty"`
+	Facts     []CardFact    `json:"facts,omitempty"`
+	Items     []CardElement `json:"items,omitempty"`
+}
+
+type CardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// Teams sends notifications as Adaptive Cards to Microsoft Teams incoming webhooks
+type Teams struct {
+	infohook, alerthook, environment string
+	severityHooks                    map[Severity]string
+	testMode                         bool
+	client                           *http.Client
+}
+
+type TeamsOption func(*Teams)
+
+// WithTeamsHTTPClient sets the client used to post to the webhooks.
+// The default client has a timeout of DefaultHTTPTimeout.
+func WithTeamsHTTPClient(c *http.Client) TeamsOption {
+	return func(t *Teams) {
+		t.client = c
+	}
+}
+
+// WithTeamsSeverityHooks sets the webhooks of the severities that don't go to the info or alert hook,
+// e.g. a channel for warnings
+func WithTeamsSeverityHooks(hooks map[Severity]string) TeamsOption {
+	return func(t *Teams) {
+		t.severityHooks = hooks
+	}
+}
+
+func NewTeams(infohook, alerthook, environment string, testMode bool, opts ...TeamsOption) *Teams {
+	t := &Teams{
+		infohook:    infohook,
+		alerthook:   alerthook,
+		environment: environment,
+		testMode:    testMode,
+		client:      defaultHTTPClient,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Info sends an info message, it is a shorthand for Send with SeverityInfo
+func (t *Teams) Info(headline, msg string) error {
+	return t.Send(context.Background(), Message{Severity: SeverityInfo, Title: headline, Body: msg, Timestamp: time.Now()})
+}
+
+// Alert sends an alert message, it is a shorthand for Send with SeverityAlert
+func (t *Teams) Alert(headline, msg string) error {
+	return t.Send(context.Background(), Message{Severity: SeverityAlert, Title: headline, Body: msg, Timestamp: time.Now()})
+}
+
+// Send posts the message to the hook of its severity if there is one, otherwise to the alert hook
+// if it is an alert and to the info hook if it isn't
+func (t *Teams) Send(ctx context.Context, msg Message) error {
+	hook, ok := t.severityHooks[msg.Severity]
+	if !ok {
+		hook = t.infohook
+		if msg.Severity >= SeverityAlert {
+			hook = t.alerthook
+		}
+	}
+
+	card := formatCard(msg, t.environment)
+	if t.testMode {
+		card.Body = append(card.Body, CardElement{
+			Type:      "Container",
+			Separator: true,
+			Items: []CardElement{
+				{Type: "Image", URL: testIconURL, AltText: "testing", Size: "Small"},
+				{Type: "TextBlock", Text: "**" + testBanner + "**", Wrap: true},
+			},
+		})
+	}
+
+	return postJSON(ctx, t.client, hook, &TeamsPayload{
+		Type: "message",
+		Attachments: []TeamsAttachment{
+			{ContentType: adaptiveCardContentType, Content: card},
+		},
+	}, nil)
+}
+
+func formatCard(msg Message, environment string) AdaptiveCard {
+	style := msg.Severity.style()
+	header := CardElement{Type: "Container", Style: "accent"}
+	icon := CardElement{Type: "Image", URL: style.icon, AltText: style.alt, Size: "Small"}
+	title := CardElement{Type: "TextBlock", Text: msg.Title, Weight: "Bolder", Size: "Medium", Wrap: true}
+	// adaptive cards only have a few named colours
+	switch {
+	case msg.Severity >= SeverityAlert:
+		header.Style = "attention"
+		title.Color = "Attention"
+	case msg.Severity == SeverityWarning:
+		header.Style = "warning"
+		title.Color = "Warning"
+	case msg.Severity == SeverityDebug:
+		header.Style = "emphasis"
+	}
+	header.Items = []CardElement{icon, title}
+
+	body := []CardElement{header}
+	if msg.Body != "" {
+		body = append(body, CardElement{Type: "TextBlock", Text: msg.Body, Wrap: true})
+	}
+
+	var facts []CardFact
+	for _, f := range msg.Fields {
+		facts = append(facts, CardFact{Title: f.Key, Value: f.Value})
+	}
+	if msg.Err != nil {
+		facts = append(facts, CardFact{Title: "Error", Value: msg.Err.Error()})
+	}
+	if msg.Source != "" {
+		facts = append(facts, CardFact{Title: "Source", Value: msg.Source})
+	}
+	if len(facts) > 0 {
+		body = append(body, CardElement{Type: "FactSet", Facts: facts})
+	}
+
+	body = append(body, CardElement{
+		Type:      "TextBlock",
+		Text:      fmt.Sprintf("Environment: **%s**", environment),
+		Wrap:      true,
+		Separator: true,
+	})
+
+	var actions []CardAction
+	for _, l := range msg.Links {
+		actions = append(actions, CardAction{Type: "Action.OpenUrl", Title: l.Text, URL: l.URL})
+	}
+
+	return AdaptiveCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.2",
+		Body:    body,
+		Actions: actions,
+		MSTeams: &CardMSTeams{Width: "Full"},
+	}
+}
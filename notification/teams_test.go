@@ -0,0 +1,93 @@
+package notification_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+// teamsServer stands in for the Teams incoming webhooks, it records the payloads per path
+func teamsServer(t *testing.T) (*httptest.Server, map[string][]notification.TeamsPayload) {
+	received := map[string][]notification.TeamsPayload{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload notification.TeamsPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("could not decode payload: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received[r.URL.Path] = append(received[r.URL.Path], payload)
+		_, _ = w.Write([]byte("1"))
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func cardText(card notification.AdaptiveCard) string {
+	b, _ := json.Marshal(card)
+	return string(b)
+}
+
+func Test_Teams(t *testing.T) {
+	server, received := teamsServer(t)
+	teams := notification.NewTeams(server.URL+"/info", server.URL+"/alert", "prod", false)
+
+	if err := teams.Info("headline", "message"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := teams.Alert("something broke", "details"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(received["/info"]) != 1 || len(received["/alert"]) != 1 {
+		t.Fatalf("expected one message per hook, got: %v", received)
+	}
+
+	alert := received["/alert"][0]
+	if alert.Type != "message" || len(alert.Attachments) != 1 || alert.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("unexpected payload: %+v", alert)
+	}
+	card := alert.Attachments[0].Content
+	if card.Type != "AdaptiveCard" || card.Body[0].Style != "attention" {
+		t.Errorf("expected an alert styled card, got: %+v", card)
+	}
+	for _, want := range []string{"something broke", "details", "Environment: **prod**"} {
+		if !strings.Contains(cardText(card), want) {
+			t.Errorf("expected the card to contain %q", want)
+		}
+	}
+	if strings.Contains(cardText(card), "TEST") {
+		t.Errorf("expected no test banner outside of test mode")
+	}
+	if received["/info"][0].Attachments[0].Content.Body[0].Style != "accent" {
+		t.Errorf("expected an info styled card")
+	}
+}
+
+func Test_Teams_TestModeAndErrors(t *testing.T) {
+	server, received := teamsServer(t)
+	teams := notification.NewTeams(server.URL+"/info", server.URL+"/alert", "dev", true)
+
+	if err := teams.Alert("a", "b"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(cardText(received["/alert"][0].Attachments[0].Content), "THE ABOVE IS A TEST") {
+		t.Errorf("expected a test banner in test mode")
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer failing.Close()
+
+	var statusErr *notification.StatusError
+	err := notification.NewTeams(failing.URL, failing.URL, "dev", false).Info("a", "b")
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a StatusError, got: %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+const (
+	DefaultSignatureHeader = "X-Signature-256"
+	DefaultTimestampHeader = "X-Signature-Timestamp"
+)
+
+// WebhookConfig configures a Webhook
+type WebhookConfig struct {
+	// URL receives the messages that have no URL in SeverityURLs. Sending a message that has neither is an error.
+	URL          string
+	SeverityURLs map[Severity]string
+	Headers      map[string]string
+	// Template is a text/template rendering the request body from a WebhookData.
+	// The "json" function encodes a value as JSON. If it is empty the WebhookData is posted as JSON.
+	Template    string
+	ContentType string
+	// Secret enables signing of the requests, see SignWebhook
+	Secret          string
+	SignatureHeader string
+	TimestampHeader string
+	Environment     string
+}
+
+// WebhookData is what the body of a webhook request is rendered from
+type WebhookData struct {
+	Severity    string            `json:"severity"`
+	Title       string            `json:"title"`
+	Body        string            `json:"body,omitempty"`
+	Fields      map[string]string `json:"fields,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	Links       []Link            `json:"links,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Source      string            `json:"source,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Timestamp   time.Time         `json:"timestamp"`
+	// Message is the message as it was sent, it isn't part of the default JSON body
+	Message Message `json:"-"`
+}
+
+// Webhook posts messages to any endpoint accepting webhooks, with an optional HMAC-SHA256 signature
+type Webhook struct {
+	cfg    WebhookConfig
+	tmpl   *template.Template
+	client *http.Client
+}
+
+type WebhookOption func(*Webhook)
+
+// WithWebhookHTTPClient sets the client used to post to the webhook.
+// The default client has a timeout of DefaultHTTPTimeout.
+func WithWebhookHTTPClient(c *http.Client) WebhookOption {
+	return func(w *Webhook) {
+		w.client = c
+	}
+}
+
+// NewWebhook returns a Webhook, an error is returned if the template can't be parsed
+func NewWebhook(cfg WebhookConfig, opts ...WebhookOption) (*Webhook, error) {
+	if cfg.URL == "" && len(cfg.SeverityURLs) == 0 {
+		return nil, errors.New("webhook needs a URL")
+	}
+	if cfg.ContentType == "" {
+		cfg.ContentType = "application/json"
+	}
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = DefaultSignatureHeader
+	}
+	if cfg.TimestampHeader == "" {
+		cfg.TimestampHeader = DefaultTimestampHeader
+	}
+
+	w := &Webhook{
+		cfg:    cfg,
+		client: defaultHTTPClient,
+	}
+
+	if cfg.Template != "" {
+		tmpl, err := template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(cfg.Template)
+		if err != nil {
+			return nil, err
+		}
+		w.tmpl = tmpl
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w, nil
+}
+
+// Send renders the message and posts it to the URL of its severity
+func (w *Webhook) Send(ctx context.Context, msg Message) error {
+	url, ok := w.cfg.SeverityURLs[msg.Severity]
+	if !ok {
+		url = w.cfg.URL
+	}
+	if url == "" {
+		return fmt.Errorf("webhook has no URL for %s messages", msg.Severity)
+	}
+
+	body, err := w.render(msg)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	for k, v := range w.cfg.Headers {
+		header.Set(k, v)
+	}
+	if w.cfg.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		header.Set(w.cfg.TimestampHeader, timestamp)
+		header.Set(w.cfg.SignatureHeader, SignWebhook(w.cfg.Secret, timestamp, body))
+	}
+
+	resp, err := post(ctx, w.client, url, w.cfg.ContentType, body, header)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+func (w *Webhook) render(msg Message) ([]byte, error) {
+	data := WebhookData{
+		Severity:    msg.Severity.String(),
+		Title:       msg.Title,
+		Body:        msg.Body,
+		Links:       msg.Links,
+		Labels:      msg.Labels,
+		Source:      msg.Source,
+		Environment: w.cfg.Environment,
+		Timestamp:   msg.Timestamp,
+		Message:     msg,
+	}
+	if len(msg.Fields) > 0 {
+		data.Fields = map[string]string{}
+		for _, f := range msg.Fields {
+			data.Fields[f.Key] = f.Value
+		}
+	}
+	if msg.Err != nil {
+		data.Error = msg.Err.Error()
+	}
+
+	if w.tmpl == nil {
+		return json.Marshal(&data)
+	}
+
+	var buf bytes.Buffer
+	if err := w.tmpl.Execute(&buf, &data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SignWebhook returns the signature of a webhook request, "sha256=" followed by the hex encoded
+// HMAC-SHA256 of the timestamp, a dot and the body. Receivers verify a request by computing the signature
+// with the timestamp header and comparing it to the signature header, and should reject old timestamps.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp + "."))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,79 @@
+package notification_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+func Test_Webhook(t *testing.T) {
+	type request struct {
+		path, body, signature, timestamp, token string
+	}
+	var received []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = append(received, request{
+			path:      r.URL.Path,
+			body:      string(body),
+			signature: r.Header.Get(notification.DefaultSignatureHeader),
+			timestamp: r.Header.Get(notification.DefaultTimestampHeader),
+			token:     r.Header.Get("X-Token"),
+		})
+	}))
+	defer server.Close()
+
+	webhook, err := notification.NewWebhook(notification.WebhookConfig{
+		URL:          server.URL + "/events",
+		SeverityURLs: map[notification.Severity]string{notification.SeverityAlert: server.URL + "/incidents"},
+		Headers:      map[string]string{"X-Token": "token"},
+		Template:     `{"summary": {{json .Title}}, "env": {{json .Environment}}}`,
+		Secret:       "secret",
+		Environment:  "prod",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = webhook.Send(context.Background(), notification.Message{Severity: notification.SeverityInfo, Title: `say "hi"`})
+	_ = webhook.Send(context.Background(), notification.Message{Severity: notification.SeverityAlert, Title: "down"})
+
+	if len(received) != 2 || received[0].path != "/events" || received[1].path != "/incidents" {
+		t.Fatalf("unexpected requests: %+v", received)
+	}
+	if want := `{"summary": "say \"hi\"", "env": "prod"}`; received[0].body != want {
+		t.Errorf("expected body %s, got: %s", want, received[0].body)
+	}
+	for _, r := range received {
+		want := notification.SignWebhook("secret", r.timestamp, []byte(r.body))
+		if r.timestamp == "" || !hmac.Equal([]byte(want), []byte(r.signature)) {
+			t.Errorf("invalid signature %q for %s", r.signature, r.body)
+		}
+		if r.token != "token" {
+			t.Errorf("expected the configured headers to be sent")
+		}
+	}
+}
+
+func Test_NewWebhook_InvalidTemplate(t *testing.T) {
+	if _, err := notification.NewWebhook(notification.WebhookConfig{URL: "http://localhost", Template: "{{"}); err == nil {
+		t.Errorf("expected an error for an invalid template")
+	}
+}
+
+func Test_Webhook_NoURLForSeverity(t *testing.T) {
+	webhook, err := notification.NewWebhook(notification.WebhookConfig{
+		SeverityURLs: map[notification.Severity]string{notification.SeverityAlert: "http://localhost/incidents"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := webhook.Send(context.Background(), notification.Message{Severity: notification.SeverityInfo}); err == nil {
+		t.Errorf("expected an error for a message without a URL")
+	}
+}
@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Labels set by Core
+const (
+	// LabelLevel is the zap level of a log entry
+	LabelLevel = "level"
+	// LabelLogger is the name of the zap logger of a log entry
+	LabelLogger = "logger"
+)
+
+type CoreOption func(*Core)
+
+// WithCoreSource sets the Source of the messages, e.g. the name of the service
+func WithCoreSource(source string) CoreOption {
+	return func(c *Core) {
+		c.source = source
+	}
+}
+
+// WithCoreDedup sets the options of the Deduplicator that the messages are sent through
+func WithCoreDedup(opts ...DedupOption) CoreOption {
+	return func(c *Core) {
+		c.dedupOpts = opts
+	}
+}
+
+// WithAlertLevel sets the lowest level that is sent as an alert, the default is zapcore.ErrorLevel.
+// Entries below it are sent as warnings at most.
+func WithAlertLevel(level zapcore.Level) CoreOption {
+	return func(c *Core) {
+		c.alertLevel = level
+	}
+}
+
+// Core is a zapcore.Core that sends log entries as messages. Tee it with the core of a logger
+// to get notified about the entries of the logger:
+//
+//	core := notification.NewCore(sender, zapcore.ErrorLevel)
+//	logger = logger.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core { return zapcore.NewTee(c, core) }))
+//
+// The message of the entry is the title of the message and the fields are its fields, a field with an error
+// is the error of the message. The messages are sent through a Deduplicator, so repeats of an entry only
+// result in a summary. Sending is synchronous, wrap the sender in a Dispatcher to avoid slowing down logging.
+// The sender must not log to a logger with the core, that would loop.
+type Core struct {
+	zapcore.LevelEnabler
+	*coreSender
+	fields []zapcore.Field
+}
+
+// coreSender is shared by the cores created with With
+type coreSender struct {
+	sender     *Deduplicator
+	source     string
+	alertLevel zapcore.Level
+	dedupOpts  []DedupOption
+}
+
+// NewCore returns a Core sending the entries enabled by level to the sender
+func NewCore(sender Sender, level zapcore.LevelEnabler, opts ...CoreOption) *Core {
+	c := &Core{
+		LevelEnabler: level,
+		coreSender:   &coreSender{alertLevel: zapcore.ErrorLevel},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.sender = NewDeduplicator(sender, c.dedupOpts...)
+	return c
+}
+
+// With returns a Core that adds the fields to the entries
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(append([]zapcore.Field(nil), c.fields...), fields...)
+	return &clone
+}
+
+func (c *Core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write sends the entry as a message
+func (c *Core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.sender.Send(context.Background(), c.message(ent, append(append([]zapcore.Field(nil), c.fields...), fields...)))
+}
+
+// Sync does nothing, the entries are sent when they are written
+func (c *Core) Sync() error {
+	return nil
+}
+
+// Close sends the pending summaries of repeated entries
+func (c *Core) Close(ctx context.Context) error {
+	return c.sender.Close(ctx)
+}
+
+func (c *Core) message(ent zapcore.Entry, fields []zapcore.Field) Message {
+	msg := Message{
+		Severity:  levelSeverity(ent.Level),
+		Title:     ent.Message,
+		Body:      ent.Stack,
+		Labels:    map[string]string{LabelLevel: ent.Level.String()},
+		Source:    c.source,
+		Timestamp: ent.Time,
+	}
+	switch {
+	case ent.Level >= c.alertLevel && msg.Severity < SeverityAlert:
+		msg.Severity = SeverityAlert
+	case ent.Level < c.alertLevel && msg.Severity >= SeverityAlert:
+		msg.Severity = SeverityWarning
+	}
+	if ent.LoggerName != "" {
+		msg.Labels[LabelLogger] = ent.LoggerName
+	}
+
+	for _, f := range fields {
+		if f.Type == zapcore.ErrorType && msg.Err == nil {
+			if err, ok := f.Interface.(error); ok {
+				msg.Err = err
+				continue
+			}
+		}
+
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		keys := make([]string, 0, len(enc.Fields))
+		for k := range enc.Fields {
+			keys = append(keys, k)
+		}
+		// fields such as namespaces or errors with causes add several keys
+		sort.Strings(keys)
+		for _, k := range keys {
+			msg.Fields = append(msg.Fields, Field{Key: k, Value: fmt.Sprint(enc.Fields[k])})
+		}
+	}
+
+	if ent.Caller.Defined {
+		msg.Fields = append(msg.Fields, Field{Key: "Caller", Value: ent.Caller.TrimmedPath()})
+	}
+	return msg
+}
+
+// levelSeverity maps a zap level to the severity of the same name, the panic and fatal levels are critical
+func levelSeverity(level zapcore.Level) Severity {
+	switch {
+	case level >= zapcore.DPanicLevel:
+		return SeverityCritical
+	case level >= zapcore.ErrorLevel:
+		return SeverityError
+	case level >= zapcore.WarnLevel:
+		return SeverityWarning
+	case level >= zapcore.InfoLevel:
+		return SeverityInfo
+	}
+	return SeverityDebug
+}
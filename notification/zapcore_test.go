@@ -0,0 +1,62 @@
+package notification_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/SecuritasCrimePrediction/apitools-go/notification"
+)
+
+func Test_Core(t *testing.T) {
+	sent := make(chan notification.Message, 10)
+	recorder := notification.SenderFunc(func(ctx context.Context, msg notification.Message) error {
+		sent <- msg
+		return nil
+	})
+
+	clock := newFakeClock()
+	core := notification.NewCore(recorder, zapcore.WarnLevel,
+		notification.WithCoreSource("api"),
+		notification.WithCoreDedup(notification.WithDedupClock(clock)))
+	logger := zap.New(core).Named("db").With(zap.String("table", "users"))
+
+	logger.Info("not sent")
+	logger.Warn("slow query", zap.Duration("took", 2*time.Second))
+	for i := 0; i < 3; i++ {
+		logger.Error("query failed", zap.Error(errors.New("connection refused")), zap.Int("attempt", i))
+	}
+
+	warn := <-sent
+	if warn.Title != "slow query" || warn.Severity != notification.SeverityWarning || warn.Source != "api" {
+		t.Errorf("unexpected message: %+v", warn)
+	}
+	want := []notification.Field{{Key: "table", Value: "users"}, {Key: "took", Value: "2s"}}
+	if len(warn.Fields) != len(want) || warn.Fields[0] != want[0] || warn.Fields[1] != want[1] {
+		t.Errorf("expected fields %v, got: %v", want, warn.Fields)
+	}
+	if warn.Labels[notification.LabelLevel] != "warn" || warn.Labels[notification.LabelLogger] != "db" {
+		t.Errorf("unexpected labels: %v", warn.Labels)
+	}
+
+	alert := <-sent
+	if alert.Title != "query failed" || alert.Severity != notification.SeverityAlert || alert.Err == nil || alert.Err.Error() != "connection refused" {
+		t.Errorf("unexpected message: %+v", alert)
+	}
+	select {
+	case got := <-sent:
+		t.Fatalf("expected repeats to be suppressed, got: %+v", got)
+	default:
+	}
+
+	if err := core.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary := <-sent; summary.Title != "query failed" || summary.Body != "Occurred 3 times in the last 5m" {
+		t.Errorf("expected a summary, got: %+v", summary)
+	}
+}